@@ -1,20 +1,35 @@
 package internal
 
 import (
+	"GoWork_4/tools"
 	"fmt"
 	"net"
+	"sync"
 	"sync/atomic"
 )
 
 // Client 表示一个聊天客户端，用于与服务器通信。
 type Client struct {
-	conn        net.Conn      // 客户端到服务器的网络连接
+	addr        string        // 服务器地址，断线重连时使用
+	conn        net.Conn      // 客户端到服务器的网络连接，重连后会被替换，并发访问需通过 currentConn
 	name        string        // 用户昵称
+	password    string        // 登录成功时使用的密码，会话令牌失效时用于自动重新登录
 	sendChan    chan string   // 发送消息通道（缓冲大小为10）
 	receiveChan chan string   // 接收消息通道（缓冲大小为10）
 	errorChan   chan error    // 错误信息通道（缓冲大小为1）
 	done        chan struct{} // 通知所有goroutine退出的信号通道
 	isConnected int32         // 原子变量表示是否处于连接状态（1=连接中，0=未连接）
+
+	reconnecting int32 // 原子变量表示是否正在断线重连（1=重连中）
+	loggingOut   int32 // 原子变量表示用户是否已主动退出登录，退出后断线不再重连
+
+	mu           sync.Mutex // 保护 conn、sessionToken 和 lastStreamID
+	sessionToken string     // 服务器下发的会话令牌，用于断线后恢复会话
+	lastStreamID string     // 最近收到的聊天消息的 Stream ID，重连后从这里补齐消息
+
+	promptMu       sync.Mutex        // 保护 awaitingPrompt，使其与向 prompts 投递提示保持一致
+	awaitingPrompt bool              // 输入循环是否正在等待交互命令的后续提示
+	prompts        chan serverPrompt // 交互命令执行过程中服务器发来的提示（缓冲大小为1）
 }
 
 // NewClient 创建一个新的客户端实例，并初始化相关字段。
@@ -24,6 +39,7 @@ func NewClient() *Client {
 		sendChan:    make(chan string, 10),
 		receiveChan: make(chan string, 10),
 		errorChan:   make(chan error, 1),
+		prompts:     make(chan serverPrompt, 1),
 		done:        make(chan struct{}),
 		isConnected: 1,
 	}
@@ -37,7 +53,8 @@ func (c *Client) Connect(addr string) error {
 	if err != nil {
 		return err
 	}
-	c.conn = conn
+	c.addr = addr
+	c.setConn(conn)
 	atomic.StoreInt32(&c.isConnected, 1)
 	return nil
 }
@@ -52,9 +69,9 @@ func (c *Client) Start() {
 		return
 	}
 
-	go c.safeReceiveFromServer() // safeReceiveFromServer 在 client_io.go 中
-	go c.safeSendToServer()      // safeSendToServer 在 client_io.go 中
-	go c.safeHandleMessages()    // safeHandleMessages 在 client_io.go 中
+	go c.safeReceiveFromServer(c.conn) // safeReceiveFromServer 在 client_io.go 中
+	go c.safeSendToServer()            // safeSendToServer 在 client_io.go 中
+	go c.safeHandleMessages()          // safeHandleMessages 在 client_io.go 中
 
 	c.userInputLoop() // userInputLoop 在 client_io.go 中
 }
@@ -81,8 +98,8 @@ func (c *Client) cleanup() {
 	if atomic.CompareAndSwapInt32(&c.isConnected, 1, 0) {
 		fmt.Println("正在清理资源...")
 
-		if c.conn != nil {
-			c.conn.Close()
+		if conn := c.currentConn(); conn != nil {
+			conn.Close()
 		}
 
 		select {
@@ -95,6 +112,50 @@ func (c *Client) cleanup() {
 	}
 }
 
+// currentConn 返回当前使用的网络连接
+func (c *Client) currentConn() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+// setConn 替换当前使用的网络连接
+func (c *Client) setConn(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn = conn
+}
+
+// setSessionToken 保存服务器下发的会话令牌
+func (c *Client) setSessionToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionToken = token
+}
+
+// getSessionToken 返回当前保存的会话令牌，未登录时为空
+func (c *Client) getSessionToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionToken
+}
+
+// advanceStreamID 记录收到的聊天消息 ID，只向前推进
+func (c *Client) advanceStreamID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastStreamID == "" || tools.CompareStreamIDs(id, c.lastStreamID) > 0 {
+		c.lastStreamID = id
+	}
+}
+
+// getLastStreamID 返回最近收到的聊天消息 ID
+func (c *Client) getLastStreamID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastStreamID
+}
+
 // isConnectedAtomic 判断当前客户端是否仍处于连接状态。
 // 返回true表示仍在连接中，false表示已经断开。
 func (c *Client) isConnectedAtomic() bool {
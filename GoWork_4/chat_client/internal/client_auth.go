@@ -138,7 +138,7 @@ func (c *Client) handlePasswordInput(nextPrompt string, currentName string) (str
 	fmt.Println(nextPrompt)
 
 	for {
-		password, err := tools.ReadInput("")
+		password, err := tools.ReadPassword("")
 		if err != nil {
 			return "", fmt.Errorf("读取密码输入失败: %v", err)
 		}
@@ -153,9 +153,40 @@ func (c *Client) handlePasswordInput(nextPrompt string, currentName string) (str
 			return "", fmt.Errorf("接收最终响应失败: %v", err)
 		}
 
+		// 注册时服务器要求再次输入密码确认
+		if strings.Contains(finalResponse, "请再次输入密码") {
+			confirm, err := tools.ReadPassword(finalResponse)
+			if err != nil {
+				return "", fmt.Errorf("读取确认密码失败: %v", err)
+			}
+			if err := tools.SendMessage(c.conn, confirm); err != nil {
+				return "", fmt.Errorf("发送确认密码失败: %v", err)
+			}
+			finalResponse, err = tools.ReceiveMessage(c.conn)
+			if err != nil {
+				return "", fmt.Errorf("接收最终响应失败: %v", err)
+			}
+		}
+
+		// 账号已在别处登录，服务器询问是否断开原会话
+		if strings.Contains(finalResponse, tools.TakeoverPrompt) {
+			answer, err := tools.ReadInput(finalResponse + " ")
+			if err != nil {
+				return "", fmt.Errorf("读取回答失败: %v", err)
+			}
+			if err := tools.SendMessage(c.conn, answer); err != nil {
+				return "", fmt.Errorf("发送回答失败: %v", err)
+			}
+			finalResponse, err = tools.ReceiveMessage(c.conn)
+			if err != nil {
+				return "", fmt.Errorf("接收最终响应失败: %v", err)
+			}
+		}
+
 		// 检查是否成功进入聊天室 (仅登录成功)
 		if strings.Contains(finalResponse, "开始聊天") {
 			c.name = currentName      // 设置客户端昵称
+			c.password = password     // 保存密码，会话令牌失效时用于自动重新登录
 			return finalResponse, nil // 成功，返回给主函数处理退出
 		}
 
@@ -175,6 +206,8 @@ func (c *Client) handlePasswordInput(nextPrompt string, currentName string) (str
 		if strings.Contains(retryPrompt, "请重新输入密码") {
 			fmt.Println(retryPrompt)
 			continue // 继续密码循环
+		} else if strings.Contains(retryPrompt, "请返回主菜单") {
+			return retryPrompt, nil // 失败次数过多，返回主菜单
 		} else {
 			return "", fmt.Errorf("认证流程中收到意外的服务器响应: %s", finalResponse)
 		}
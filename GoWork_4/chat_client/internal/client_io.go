@@ -3,12 +3,33 @@ package internal
 import (
 	"GoWork_4/tools"
 	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// interactiveCommands 发送后服务器会继续提示输入（密码、二次确认）的命令
+// 发送这些命令后输入循环先等待服务器的提示，再读取标准输入作为回答
+var interactiveCommands = map[string]bool{
+	"/passwd":        true,
+	"/deleteaccount": true,
+}
+
+// promptTimeout 等待交互命令下一条提示的最长时间，超时后认为命令已结束，恢复普通输入
+// 需要覆盖服务器校验密码（bcrypt）的耗时
+const promptTimeout = 5 * time.Second
+
+// serverPrompt 服务器在交互命令执行过程中发来的提示
+type serverPrompt struct {
+	text     string // 提示文本
+	password bool   // 是否要求输入密码
+}
+
 // safeReceiveFromServer 在独立协程中安全地从服务器接收数据。
 // 使用select监听done通道以优雅关闭该协程。
-// 若接收到的消息出错会通过errorChan传递错误。
-func (c *Client) safeReceiveFromServer() {
+// 若接收到的消息出错会通过errorChan传递错误。每条连接对应一个接收协程，重连后启动新的协程。
+func (c *Client) safeReceiveFromServer(conn net.Conn) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Printf("接收协程发生panic: %v\n", r)
@@ -20,7 +41,7 @@ func (c *Client) safeReceiveFromServer() {
 		case <-c.done:
 			return
 		default:
-			msg, err := tools.ReceiveMessage(c.conn)
+			msg, err := tools.ReceiveMessage(conn)
 			if err != nil {
 				select {
 				case c.errorChan <- fmt.Errorf("与服务器断开连接: %v", err):
@@ -40,7 +61,7 @@ func (c *Client) safeReceiveFromServer() {
 
 // safeSendToServer 在独立协程中安全地向服务器发送数据。
 // 监听sendChan中的消息并通过网络连接发送出去。
-// 出现发送错误时将错误放入errorChan。
+// 出现发送错误时将错误放入errorChan，协程继续运行，重连后使用新连接发送。
 func (c *Client) safeSendToServer() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -56,12 +77,11 @@ func (c *Client) safeSendToServer() {
 			if !ok {
 				return
 			}
-			if err := tools.SendMessage(c.conn, msg); err != nil {
+			if err := tools.SendMessage(c.currentConn(), msg); err != nil {
 				select {
 				case c.errorChan <- fmt.Errorf("发送消息失败: %v", err):
 				default:
 				}
-				return
 			}
 		}
 	}
@@ -69,7 +89,7 @@ func (c *Client) safeSendToServer() {
 
 // safeHandleMessages 在独立协程中处理来自服务器的消息以及错误事件。
 // 当收到消息时调用工具函数打印出来并在控制台输出提示符。
-// 收到错误后调用handleConnectionError方法处理异常情况。
+// 收到错误后尝试断线重连，重连失败时调用handleConnectionError方法处理异常情况。
 func (c *Client) safeHandleMessages() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -85,6 +105,28 @@ func (c *Client) safeHandleMessages() {
 			if !ok {
 				return
 			}
+			// 会话令牌消息只保存，不显示
+			if token, ok := tools.ParseSessionToken(msg); ok {
+				c.setSessionToken(token)
+				continue
+			}
+			// 账号在别处登录或被管理员移出，服务器即将断开连接，不再自动重连
+			if tools.IsTerminalNotice(msg) {
+				atomic.StoreInt32(&c.loggingOut, 1)
+				c.setSessionToken("")
+			}
+			// 交互命令的提示交给正在等待的输入循环读取回答；没有在等待时按普通消息显示
+			if text, password, ok := tools.ParsePrompt(msg); ok {
+				if c.deliverPrompt(serverPrompt{text: text, password: password}) {
+					continue
+				}
+				msg = text
+			}
+			// 聊天消息附带 Stream ID，记录下来用于重连后补齐
+			if id, text, ok := tools.ParseStreamMessage(msg); ok {
+				c.advanceStreamID(id)
+				msg = text
+			}
 			// 使用tools包的PrintMessage显示消息
 			tools.PrintMessage("", msg)
 
@@ -92,6 +134,12 @@ func (c *Client) safeHandleMessages() {
 			if !ok {
 				return
 			}
+			if !c.isConnectedAtomic() {
+				return // 用户已主动退出
+			}
+			if c.reconnect(err) { // reconnect 在 client_reconnect.go 中
+				continue
+			}
 			c.handleConnectionError(err) // handleConnectionError 在 client.go 中
 			return
 		}
@@ -117,7 +165,7 @@ func (c *Client) userInputLoop() {
 				continue
 			}
 
-			if input == "/exit" {
+			if input == "/exit" || input == "/quit" {
 				fmt.Println("再见！")
 				c.cleanup()
 				return
@@ -127,13 +175,89 @@ func (c *Client) userInputLoop() {
 				continue
 			}
 
+			if atomic.LoadInt32(&c.reconnecting) == 1 {
+				fmt.Println("正在重新连接，消息未发送")
+				continue
+			}
+
+			if input == "/logout" {
+				// 主动退出登录后不再保留令牌，服务器断开连接时也不再重连
+				atomic.StoreInt32(&c.loggingOut, 1)
+				c.setSessionToken("")
+			}
+
 			select {
 			case c.sendChan <- input:
 			case <-c.done:
 				return
 			default:
 				fmt.Println("发送队列已满，请稍后再试")
+				continue
+			}
+
+			if interactiveCommands[strings.Fields(input)[0]] {
+				c.answerPrompts()
+			}
+		}
+	}
+}
+
+// answerPrompts 在发送交互命令后依次回答服务器的提示，直到一段时间内没有新的提示
+// 提示到达之前不读取标准输入，因此提示前输入的聊天内容不会被当作回答，回答也不会被当作聊天消息发送；
+// 密码提示以不回显的方式读取，不会留在终端回滚记录中
+func (c *Client) answerPrompts() {
+	c.promptMu.Lock()
+	c.awaitingPrompt = true
+	c.promptMu.Unlock()
+	defer func() {
+		c.promptMu.Lock()
+		c.awaitingPrompt = false
+		// 等待结束前刚好投递的提示按普通消息显示
+		select {
+		case p := <-c.prompts:
+			tools.PrintMessage("", p.text)
+		default:
+		}
+		c.promptMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(promptTimeout):
+			return
+		case p := <-c.prompts:
+			var answer string
+			var err error
+			if p.password {
+				answer, err = tools.ReadPassword(p.text)
+			} else {
+				answer, err = tools.ReadInput(p.text)
+			}
+			if err != nil {
+				return
+			}
+			select {
+			case c.sendChan <- answer:
+			case <-c.done:
+				return
 			}
 		}
 	}
 }
+
+// deliverPrompt 将提示交给正在等待的输入循环，输入循环没有在等待时返回 false
+func (c *Client) deliverPrompt(p serverPrompt) bool {
+	c.promptMu.Lock()
+	defer c.promptMu.Unlock()
+	if !c.awaitingPrompt {
+		return false
+	}
+	select {
+	case c.prompts <- p:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"GoWork_4/tools"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	reconnectBaseDelay   = 500 * time.Millisecond // 第一次重连前的等待时间
+	reconnectMaxDelay    = 30 * time.Second       // 重连等待时间上限
+	maxReconnectAttempts = 10                     // 放弃前最多尝试的次数
+	reconnectDialTimeout = 5 * time.Second        // 单次建立连接的超时时间
+	reauthTimeout        = 10 * time.Second       // 重新登录流程的超时时间
+	catchUpCount         = 50                     // 重连后补齐消息时请求的条数
+)
+
+// errAuthRejected 服务器明确拒绝了自动登录，继续重试没有意义
+var errAuthRejected = errors.New("服务器拒绝登录")
+
+// reconnect 在连接断开后按指数退避重新连接并自动登录
+// 参数 cause 是导致断开的错误
+// 返回值表示是否重连成功，失败时调用方负责清理资源
+func (c *Client) reconnect(cause error) bool {
+	if atomic.LoadInt32(&c.loggingOut) == 1 || !c.isConnectedAtomic() {
+		return false
+	}
+	atomic.StoreInt32(&c.reconnecting, 1)
+	defer atomic.StoreInt32(&c.reconnecting, 0)
+
+	fmt.Printf("\r连接已断开（%v），正在重新连接…\n", cause)
+	if old := c.currentConn(); old != nil {
+		old.Close()
+	}
+
+	for attempt := 0; attempt < maxReconnectAttempts; attempt++ {
+		delay := backoffDelay(attempt)
+		fmt.Printf("正在重新连接…（第 %d 次，%s 后重试）\n", attempt+1, delay.Round(100*time.Millisecond))
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(delay):
+		}
+
+		conn, err := net.DialTimeout("tcp", c.addr, reconnectDialTimeout)
+		if err != nil {
+			continue
+		}
+		if err := c.reauthenticate(conn); err != nil {
+			conn.Close()
+			if errors.Is(err, errAuthRejected) {
+				fmt.Printf("自动登录失败: %v\n", err)
+				return false
+			}
+			continue
+		}
+
+		c.setConn(conn)
+		c.drainErrors()
+		go c.safeReceiveFromServer(conn)
+		fmt.Println("重新连接成功")
+		c.requestCatchUp()
+		return true
+	}
+	fmt.Println("重新连接失败次数过多，已放弃")
+	return false
+}
+
+// backoffDelay 计算第 attempt 次重连前的等待时间：指数增长并加入最多 50% 的随机抖动
+func backoffDelay(attempt int) time.Duration {
+	delay := reconnectBaseDelay << attempt
+	if delay <= 0 || delay > reconnectMaxDelay {
+		delay = reconnectMaxDelay
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/2+1))
+}
+
+// reauthenticate 在新连接上自动登录
+// 优先使用会话令牌恢复会话，令牌失效时改用登录成功时保存的昵称和密码
+func (c *Client) reauthenticate(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(reauthTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := tools.ReceiveMessage(conn); err != nil { // 主菜单
+		return err
+	}
+
+	if token := c.getSessionToken(); token != "" {
+		if err := tools.SendMessage(conn, tools.ResumeCommand+" "+token); err != nil {
+			return err
+		}
+		resp, err := tools.ReceiveMessage(conn)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(resp, "开始聊天") {
+			fmt.Println(resp)
+			return nil
+		}
+		if strings.Contains(resp, "已在线") {
+			// 服务器可能还没发现旧连接已断开，稍后重试
+			return fmt.Errorf("%s", resp)
+		}
+		// 令牌已失效，服务器会重新发送主菜单，改用密码登录
+		c.setSessionToken("")
+		if _, err := tools.ReceiveMessage(conn); err != nil {
+			return err
+		}
+	}
+
+	if c.name == "" || c.password == "" {
+		return fmt.Errorf("%w：没有可用的登录凭证", errAuthRejected)
+	}
+	if err := tools.SendMessage(conn, "1"); err != nil {
+		return err
+	}
+	if _, err := tools.ReceiveMessage(conn); err != nil { // 请输入昵称
+		return err
+	}
+	if err := tools.SendMessage(conn, c.name); err != nil {
+		return err
+	}
+	resp, err := tools.ReceiveMessage(conn)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(resp, "已在线") {
+		return fmt.Errorf("%s", resp)
+	}
+	if !strings.Contains(resp, "请输入密码") {
+		return fmt.Errorf("%w：%s", errAuthRejected, resp)
+	}
+	if err := tools.SendMessage(conn, c.password); err != nil {
+		return err
+	}
+	resp, err = tools.ReceiveMessage(conn)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(resp, tools.TakeoverPrompt) {
+		// 自动重连不替用户做决定，不回答即断开，稍后重试时旧连接可能已被服务器清理
+		return fmt.Errorf("%s", resp)
+	}
+	if !strings.Contains(resp, "开始聊天") {
+		return fmt.Errorf("%w：%s", errAuthRejected, resp)
+	}
+	fmt.Println(resp)
+	return nil
+}
+
+// drainErrors 丢弃断线期间积压的错误，避免重连成功后被旧错误再次触发重连
+func (c *Client) drainErrors() {
+	for {
+		select {
+		case <-c.errorChan:
+		default:
+			return
+		}
+	}
+}
+
+// requestCatchUp 请求断线期间错过的聊天消息
+func (c *Client) requestCatchUp() {
+	lastID := c.getLastStreamID()
+	if lastID == "" {
+		return
+	}
+	select {
+	case c.sendChan <- fmt.Sprintf("/history %d --after %s", catchUpCount, lastID):
+		fmt.Println("正在获取断线期间错过的消息…")
+	default:
+	}
+}
@@ -0,0 +1,59 @@
+// Package auth 定义用户账号存储接口及其 MySQL、htpasswd 文件和内存三种实现
+// 服务器只依赖 UserStore 接口，后端由配置 CHAT_AUTH_BACKEND 选择
+package auth
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/nickname"
+	"fmt"
+)
+
+// 账号存储后端名称，对应配置 CHAT_AUTH_BACKEND 的取值
+const (
+	BackendMySQL  = "mysql"  // 存储在 MySQL users 表中
+	BackendFile   = "file"   // 存储在 htpasswd 格式的文件中
+	BackendMemory = "memory" // 只保存在内存中，重启后丢失，用于开发和测试
+)
+
+// Authenticator 校验用户名和密码
+type Authenticator interface {
+	// Verify 检查用户名和密码是否匹配，用户不存在时返回 false 而不是错误
+	Verify(name, password string) (bool, error)
+}
+
+// UserStore 用户账号存储
+type UserStore interface {
+	Authenticator
+
+	// Exists 检查用户名是否已注册
+	Exists(name string) (bool, error)
+	// Register 注册新用户，密码以哈希形式保存
+	Register(name, password string) error
+	// UpdatePassword 修改已注册用户的密码
+	UpdatePassword(name, password string) error
+	// Delete 删除用户账号
+	Delete(name string) error
+	// FindConfusable 查找与 name 骨架相同（仅大小写或形近字符不同）的已注册用户名
+	FindConfusable(name string) (similar string, found bool, err error)
+}
+
+// findConfusable 在用户名集合中查找与 name 骨架相同的用户名，供文件和内存后端使用
+func findConfusable(names map[string]string, name string) (string, bool) {
+	skeleton := nickname.Skeleton(name)
+	for existing := range names {
+		if nickname.Skeleton(existing) == skeleton {
+			return existing, true
+		}
+	}
+	return "", false
+}
+
+// errUserExists 注册时用户名已存在或与已注册用户名的骨架相同
+func errUserExists(name string) error {
+	return fmt.Errorf("注册用户 '%s' 失败：%w", name, db.ErrNameConflict)
+}
+
+// errUserNotFound 修改或删除时用户不存在
+func errUserNotFound(name string) error {
+	return fmt.Errorf("用户 '%s' 不存在", name)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileStore 基于 htpasswd 格式文件的账号存储
+// 文件每行一个账号，格式为 "用户名:bcrypt哈希"，以 # 开头的行和空行被忽略，
+// 因此可以直接使用 `htpasswd -B` 生成的文件。每次修改后整体重写文件。
+type FileStore struct {
+	*MemoryStore
+	path string // 账号文件路径
+}
+
+// NewFileStore 从 htpasswd 文件加载账号，文件不存在时在首次注册时创建
+// 参数 cost 是新密码的 bcrypt 代价
+func NewFileStore(path string, cost int) (*FileStore, error) {
+	users, err := readHtpasswd(path)
+	if err != nil {
+		return nil, err
+	}
+	// 文件不存在时要求所在目录存在，否则要到第一次注册时才发现无法写入
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("账号文件所在目录 %s 不存在", filepath.Dir(path))
+	}
+	f := &FileStore{MemoryStore: NewMemoryStore(cost), path: path}
+	f.users = users
+	f.persist = f.write
+	return f, nil
+}
+
+// Path 返回账号文件路径
+func (f *FileStore) Path() string {
+	return f.path
+}
+
+// readHtpasswd 读取 htpasswd 文件，文件不存在时返回空表
+func readHtpasswd(path string) (map[string]string, error) {
+	users := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return users, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取账号文件失败：%w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(line, ":")
+		if !ok || name == "" || !strings.HasPrefix(hash, "$2") {
+			return nil, fmt.Errorf("账号文件 %s 第 %d 行格式错误，应为 用户名:bcrypt哈希", path, lineNo)
+		}
+		users[name] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取账号文件失败：%w", err)
+	}
+	return users, nil
+}
+
+// write 将账号表按用户名排序后写入临时文件，再替换原文件，避免写到一半时崩溃导致文件损坏
+func (f *FileStore) write(users map[string]string) error {
+	var buf bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(users)) {
+		fmt.Fprintf(&buf, "%s:%s\n", name, users[name])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("写入账号文件失败：%w", err)
+	}
+	defer os.Remove(tmp.Name()) // 出错时清理临时文件，重命名成功后文件已不存在，删除失败可忽略
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入账号文件失败：%w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入账号文件失败：%w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入账号文件失败：%w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("写入账号文件失败：%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.htpasswd")
+
+	f, err := NewFileStore(path, testCost)
+	if err != nil {
+		t.Fatalf("NewFileStore 失败：%v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("首次注册前不应创建账号文件，Stat 错误为 %v", err)
+	}
+	for _, name := range []string{"carol", "alice", "bob"} {
+		if err := f.Register(name, name+"-pw"); err != nil {
+			t.Fatalf("注册 %s 失败：%v", name, err)
+		}
+	}
+	if err := f.UpdatePassword("bob", "bob-new"); err != nil {
+		t.Fatalf("修改密码失败：%v", err)
+	}
+	if err := f.Delete("carol"); err != nil {
+		t.Fatalf("删除账号失败：%v", err)
+	}
+
+	// 文件按用户名排序，每行一个 用户名:bcrypt哈希
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取账号文件失败：%v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "alice:$2") || !strings.HasPrefix(lines[1], "bob:$2") {
+		t.Fatalf("账号文件内容不符合预期：\n%s", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat 账号文件失败：%v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("账号文件权限为 %o，期望 600", perm)
+	}
+
+	// 写入经由临时文件重命名完成，不应留下临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("读取目录失败：%v", err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("目录中应只有账号文件，实际为 %v", names)
+	}
+
+	// 重新加载后账号和密码保持不变
+	reloaded, err := NewFileStore(path, testCost)
+	if err != nil {
+		t.Fatalf("重新加载账号文件失败：%v", err)
+	}
+	checks := []struct {
+		user     string
+		password string
+		want     bool
+	}{
+		{"alice", "alice-pw", true},
+		{"bob", "bob-new", true},
+		{"bob", "bob-pw", false},
+		{"carol", "carol-pw", false},
+	}
+	for _, c := range checks {
+		got, err := reloaded.Verify(c.user, c.password)
+		if err != nil {
+			t.Fatalf("Verify 返回错误：%v", err)
+		}
+		if got != c.want {
+			t.Errorf("重新加载后 Verify(%q, %q) = %v，期望 %v", c.user, c.password, got, c.want)
+		}
+	}
+}
+
+func TestNewFileStoreRejectsInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"注释和空行", "# 由 htpasswd -B 生成\n\nalice:$2y$04$abcdefghijklmnopqrstuu\n", false},
+		{"缺少冒号", "alice\n", true},
+		{"不是 bcrypt 哈希", "alice:plaintext\n", true},
+		{"用户名为空", ":$2y$04$abcdefghijklmnopqrstuu\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users.htpasswd")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("写入测试文件失败：%v", err)
+			}
+			_, err := NewFileStore(path, testCost)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFileStore 错误为 %v，期望出错：%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFileStoreMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "users.htpasswd")
+	if _, err := NewFileStore(path, testCost); err == nil {
+		t.Fatal("账号文件所在目录不存在时 NewFileStore 应返回错误")
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"GoWork_4/chat_server/db"
+	"maps"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryStore 只保存在内存中的账号存储，服务器重启后账号全部丢失
+// 适用于本地开发和不依赖 MySQL 的测试，也是 FileStore 的内存部分
+type MemoryStore struct {
+	mu      sync.RWMutex
+	users   map[string]string                   // 用户名到 bcrypt 哈希的映射
+	cost    int                                 // bcrypt 代价
+	persist func(users map[string]string) error // 修改后的持久化函数，为 nil 表示不持久化
+}
+
+// NewMemoryStore 创建空的内存账号存储
+// 参数 cost 是 bcrypt 代价，超出允许范围时使用默认值
+func NewMemoryStore(cost int) *MemoryStore {
+	return &MemoryStore{users: make(map[string]string), cost: cost}
+}
+
+// modify 在写锁内修改用户表并持久化，持久化失败时回滚修改
+func (m *MemoryStore) modify(change func(users map[string]string) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	backup := maps.Clone(m.users)
+	if err := change(m.users); err != nil {
+		return err
+	}
+	if m.persist != nil {
+		if err := m.persist(m.users); err != nil {
+			m.users = backup
+			return err
+		}
+	}
+	return nil
+}
+
+// Exists 检查用户名是否已注册
+func (m *MemoryStore) Exists(name string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.users[name]
+	return ok, nil
+}
+
+// Register 注册新用户
+// 用户名或骨架与已有用户冲突时返回包装了 db.ErrNameConflict 的错误，与 MySQL 后端一致
+func (m *MemoryStore) Register(name, password string) error {
+	hash, err := db.HashPassword(password, m.cost)
+	if err != nil {
+		return err
+	}
+	return m.modify(func(users map[string]string) error {
+		// 在写锁内检查，避免两个连接同时注册相似的昵称
+		if _, found := findConfusable(users, name); found {
+			return errUserExists(name)
+		}
+		users[name] = hash
+		return nil
+	})
+}
+
+// Verify 检查用户名和密码是否匹配
+func (m *MemoryStore) Verify(name, password string) (bool, error) {
+	m.mu.RLock()
+	hash, ok := m.users[name]
+	m.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, nil
+}
+
+// UpdatePassword 修改用户密码
+func (m *MemoryStore) UpdatePassword(name, password string) error {
+	hash, err := db.HashPassword(password, m.cost)
+	if err != nil {
+		return err
+	}
+	return m.modify(func(users map[string]string) error {
+		if _, ok := users[name]; !ok {
+			return errUserNotFound(name)
+		}
+		users[name] = hash
+		return nil
+	})
+}
+
+// Delete 删除用户账号
+func (m *MemoryStore) Delete(name string) error {
+	return m.modify(func(users map[string]string) error {
+		if _, ok := users[name]; !ok {
+			return errUserNotFound(name)
+		}
+		delete(users, name)
+		return nil
+	})
+}
+
+// FindConfusable 查找与 name 骨架相同的已注册用户名
+func (m *MemoryStore) FindConfusable(name string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	similar, found := findConfusable(m.users, name)
+	return similar, found, nil
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"GoWork_4/chat_server/db"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testCost 测试使用最低的 bcrypt 代价，加快哈希计算
+const testCost = bcrypt.MinCost
+
+// newTestMemoryStore 创建已注册 alice 的内存账号存储
+func newTestMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	m := NewMemoryStore(testCost)
+	if err := m.Register("alice", "secret1"); err != nil {
+		t.Fatalf("注册 alice 失败：%v", err)
+	}
+	return m
+}
+
+func TestMemoryStoreRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		conflict bool
+	}{
+		{"新用户", "bob", false},
+		{"同名用户", "alice", true},
+		{"仅大小写不同", "ALICE", true},
+		{"形近字符", "a1ice", true},
+		{"西里尔字母", "аlice", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMemoryStore(t)
+			err := m.Register(tt.user, "secret2")
+			if tt.conflict {
+				if !errors.Is(err, db.ErrNameConflict) {
+					t.Fatalf("Register(%q) 错误为 %v，期望 ErrNameConflict", tt.user, err)
+				}
+				if ok, _ := m.Exists(tt.user); ok && tt.user != "alice" {
+					t.Fatalf("冲突的用户 %q 不应被注册", tt.user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Register(%q) 失败：%v", tt.user, err)
+			}
+			if ok, _ := m.Exists(tt.user); !ok {
+				t.Fatalf("注册后 Exists(%q) 为 false", tt.user)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreVerify(t *testing.T) {
+	m := newTestMemoryStore(t)
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     bool
+	}{
+		{"密码正确", "alice", "secret1", true},
+		{"密码错误", "alice", "wrong", false},
+		{"用户不存在", "bob", "secret1", false},
+		{"用户名大小写不同", "Alice", "secret1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Verify(tt.user, tt.password)
+			if err != nil {
+				t.Fatalf("Verify 返回错误：%v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Verify(%q, %q) = %v，期望 %v", tt.user, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreUpdatePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		wantErr bool
+	}{
+		{"已注册用户", "alice", false},
+		{"用户不存在", "bob", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMemoryStore(t)
+			err := m.UpdatePassword(tt.user, "secret2")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("UpdatePassword(%q) 应返回错误", tt.user)
+				}
+				if ok, _ := m.Exists(tt.user); ok {
+					t.Fatalf("UpdatePassword 不应创建用户 %q", tt.user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdatePassword(%q) 失败：%v", tt.user, err)
+			}
+			if ok, _ := m.Verify(tt.user, "secret2"); !ok {
+				t.Fatal("新密码校验失败")
+			}
+			if ok, _ := m.Verify(tt.user, "secret1"); ok {
+				t.Fatal("旧密码仍然有效")
+			}
+		})
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		wantErr bool
+	}{
+		{"已注册用户", "alice", false},
+		{"用户不存在", "bob", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMemoryStore(t)
+			err := m.Delete(tt.user)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Delete(%q) 应返回错误", tt.user)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Delete(%q) 失败：%v", tt.user, err)
+			}
+			if ok, _ := m.Exists(tt.user); ok {
+				t.Fatalf("删除后 Exists(%q) 仍为 true", tt.user)
+			}
+			// 删除后可以重新注册相同的用户名
+			if err := m.Register(tt.user, "secret2"); err != nil {
+				t.Fatalf("删除后重新注册失败：%v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreFindConfusable(t *testing.T) {
+	m := newTestMemoryStore(t)
+	tests := []struct {
+		name      string
+		query     string
+		wantName  string
+		wantFound bool
+	}{
+		{"同名", "alice", "alice", true},
+		{"仅大小写不同", "Alice", "alice", true},
+		{"数字替换字母", "al1ce", "alice", true},
+		{"不相似", "bob", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similar, found, err := m.FindConfusable(tt.query)
+			if err != nil {
+				t.Fatalf("FindConfusable 返回错误：%v", err)
+			}
+			if found != tt.wantFound || similar != tt.wantName {
+				t.Fatalf("FindConfusable(%q) = (%q, %v)，期望 (%q, %v)", tt.query, similar, found, tt.wantName, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestMemoryStorePersistFailureRollsBack(t *testing.T) {
+	m := newTestMemoryStore(t)
+	m.persist = func(map[string]string) error { return errors.New("磁盘已满") }
+
+	if err := m.Register("bob", "secret2"); err == nil {
+		t.Fatal("持久化失败时 Register 应返回错误")
+	}
+	if ok, _ := m.Exists("bob"); ok {
+		t.Fatal("持久化失败后注册未回滚")
+	}
+	if err := m.Delete("alice"); err == nil {
+		t.Fatal("持久化失败时 Delete 应返回错误")
+	}
+	if ok, _ := m.Exists("alice"); !ok {
+		t.Fatal("持久化失败后删除未回滚")
+	}
+}
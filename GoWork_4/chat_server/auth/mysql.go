@@ -0,0 +1,44 @@
+package auth
+
+import "GoWork_4/chat_server/db"
+
+// MySQLStore 基于 MySQL users 表的账号存储
+// udb 为 nil（数据库未连接）时所有操作返回"数据库连接不可用"错误
+type MySQLStore struct {
+	udb *db.UserDB
+}
+
+// NewMySQLStore 创建基于 MySQL 的账号存储
+func NewMySQLStore(udb *db.UserDB) *MySQLStore {
+	return &MySQLStore{udb: udb}
+}
+
+// Exists 检查用户名是否已注册
+func (m *MySQLStore) Exists(name string) (bool, error) {
+	return m.udb.CheckNameExists(name)
+}
+
+// Register 注册新用户
+func (m *MySQLStore) Register(name, password string) error {
+	return m.udb.RegisterUser(name, password)
+}
+
+// Verify 检查用户名和密码是否匹配，旧格式的密码哈希会在校验成功后升级
+func (m *MySQLStore) Verify(name, password string) (bool, error) {
+	return m.udb.CheckCredentials(name, password)
+}
+
+// UpdatePassword 修改用户密码
+func (m *MySQLStore) UpdatePassword(name, password string) error {
+	return m.udb.UpdatePassword(name, password)
+}
+
+// Delete 删除用户账号
+func (m *MySQLStore) Delete(name string) error {
+	return m.udb.DeleteUser(name)
+}
+
+// FindConfusable 查找与 name 骨架相同的已注册用户名
+func (m *MySQLStore) FindConfusable(name string) (string, bool, error) {
+	return m.udb.FindConfusableUser(name)
+}
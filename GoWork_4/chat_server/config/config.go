@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 服务器运行配置
+// 所有配置项均从环境变量读取，未设置时使用默认值，便于在 docker-compose 中调整。
+type Config struct {
+	MySQLHost     string // MySQL 主机（MYSQL_HOST）
+	MySQLPort     string // MySQL 端口（MYSQL_PORT）
+	MySQLUser     string // MySQL 用户名（MYSQL_USER）
+	MySQLPassword string // MySQL 密码（MYSQL_PASSWORD）
+	MySQLDatabase string // MySQL 数据库名（MYSQL_DATABASE）
+
+	RedisAddr     string // Redis 地址（REDIS_ADDR）
+	RedisPassword string // Redis 密码（REDIS_PASSWORD）
+	RedisDB       int    // Redis 数据库编号（REDIS_DB）
+
+	Middlewares    []string // 按顺序启用的消息中间件名称（CHAT_MIDDLEWARES，逗号分隔）
+	ProfanityWords []string // 敏感词过滤中间件使用的词表（CHAT_PROFANITY_WORDS，逗号分隔）
+	MaxMessageLen  int      // 消息长度限制中间件允许的最大字符数（CHAT_MAX_MESSAGE_LEN）
+	Bots           []string // 启动时上线的进程内机器人名称（CHAT_BOTS，逗号分隔）
+	Admins         []string // 拥有管理员权限的用户名（CHAT_ADMINS，逗号分隔）
+	HistoryMaxPage int      // /history 单页最多返回的记录数（CHAT_HISTORY_MAX_PAGE）
+
+	HistoryMaxEntries      int           // 聊天历史 Stream 最多保留的条数，0 表示不限（CHAT_HISTORY_MAX_ENTRIES）
+	HistoryMaxAge          time.Duration // 聊天历史最长保留时间，0 表示不限（CHAT_HISTORY_MAX_AGE，如 72h）
+	HistoryKeepOnRestart   bool          // 重启时是否保留聊天历史和活跃度排名（CHAT_HISTORY_KEEP_ON_RESTART）
+	HistoryJanitorInterval time.Duration // 历史清理协程的执行间隔（CHAT_HISTORY_JANITOR_INTERVAL）
+
+	HTTPAddr   string // HTTP 监听地址，提供 /metrics 等接口，为空表示不启用（CHAT_HTTP_ADDR，如 :8080）
+	AdminToken string // HTTP 管理 API 的访问令牌，为空表示不启用管理 API（CHAT_ADMIN_TOKEN）
+
+	SessionSecret string        // 会话令牌的签名密钥，为空时每次启动随机生成（CHAT_SESSION_SECRET）
+	SessionTTL    time.Duration // 会话令牌有效期（CHAT_SESSION_TTL）
+
+	LoginMaxUserFailures int64         // 账号在统计窗口内允许的最大登录失败次数，达到后锁定，0 表示不锁定（CHAT_LOGIN_MAX_USER_FAILURES）
+	LoginMaxIPFailures   int64         // 单个 IP 在统计窗口内允许的最大登录失败次数，达到后锁定，0 表示不锁定（CHAT_LOGIN_MAX_IP_FAILURES）
+	LoginFailureWindow   time.Duration // 登录失败次数的统计窗口（CHAT_LOGIN_FAILURE_WINDOW）
+	LoginLockout         time.Duration // 账号或 IP 的锁定时长（CHAT_LOGIN_LOCKOUT）
+	LoginDelayStep       time.Duration // 登录失败后渐进延迟的基数，每次失败翻倍（CHAT_LOGIN_DELAY_STEP）
+	LoginMaxDelay        time.Duration // 登录失败后渐进延迟的上限（CHAT_LOGIN_MAX_DELAY）
+
+	AuthBackend string // 账号存储后端：mysql/file/memory（CHAT_AUTH_BACKEND）
+	AuthFile    string // file 后端使用的 htpasswd 格式账号文件路径（CHAT_AUTH_FILE）
+
+	DuplicateLogin string // 同一账号重复登录时的策略：reject 拒绝新登录，takeover 询问新登录后踢出旧会话，multi 允许多个会话同时在线（CHAT_DUPLICATE_LOGIN）
+
+	BcryptCost int // 密码哈希的 bcrypt 代价（4-31），调整后旧哈希会在用户下次登录时重新计算（CHAT_BCRYPT_COST）
+
+	PasswordMinLength      int  // 密码最少字符数（CHAT_PASSWORD_MIN_LENGTH）
+	PasswordMinClasses     int  // 密码至少包含的字符类别数（小写字母、大写字母、数字、符号），0 表示不限（CHAT_PASSWORD_MIN_CLASSES）
+	PasswordRejectUsername bool // 是否拒绝包含用户名的密码（CHAT_PASSWORD_REJECT_USERNAME）
+
+	LogLevel  string // 日志级别：debug/info/warn/error（CHAT_LOG_LEVEL）
+	LogFormat string // 日志格式：text/json（CHAT_LOG_FORMAT）
+}
+
+// Load 从环境变量加载服务器配置
+// 返回值为填充好默认值的配置实例
+func Load() *Config {
+	return &Config{
+		MySQLHost:     getEnv("MYSQL_HOST", "localhost"),
+		MySQLPort:     getEnv("MYSQL_PORT", "3306"),
+		MySQLUser:     getEnv("MYSQL_USER", "root"),
+		MySQLPassword: getEnv("MYSQL_PASSWORD", "231792"),
+		MySQLDatabase: getEnv("MYSQL_DATABASE", "User"),
+
+		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisDB:       getEnvInt("REDIS_DB", 2),
+
+		Middlewares:    getEnvList("CHAT_MIDDLEWARES", nil),
+		ProfanityWords: getEnvList("CHAT_PROFANITY_WORDS", nil),
+		MaxMessageLen:  getEnvInt("CHAT_MAX_MESSAGE_LEN", 500),
+		Bots:           getEnvList("CHAT_BOTS", nil),
+		Admins:         getEnvList("CHAT_ADMINS", nil),
+		HistoryMaxPage: getEnvInt("CHAT_HISTORY_MAX_PAGE", 50),
+
+		HistoryMaxEntries:      getEnvInt("CHAT_HISTORY_MAX_ENTRIES", 1000),
+		HistoryMaxAge:          getEnvDuration("CHAT_HISTORY_MAX_AGE", 0),
+		HistoryKeepOnRestart:   getEnvBool("CHAT_HISTORY_KEEP_ON_RESTART", true),
+		HistoryJanitorInterval: getEnvDuration("CHAT_HISTORY_JANITOR_INTERVAL", time.Minute),
+
+		HTTPAddr:   getEnv("CHAT_HTTP_ADDR", ""),
+		AdminToken: getEnv("CHAT_ADMIN_TOKEN", ""),
+
+		SessionSecret: getEnv("CHAT_SESSION_SECRET", ""),
+		SessionTTL:    getEnvDuration("CHAT_SESSION_TTL", 24*time.Hour),
+
+		LoginMaxUserFailures: int64(getEnvInt("CHAT_LOGIN_MAX_USER_FAILURES", 5)),
+		LoginMaxIPFailures:   int64(getEnvInt("CHAT_LOGIN_MAX_IP_FAILURES", 20)),
+		LoginFailureWindow:   getEnvDuration("CHAT_LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockout:         getEnvDuration("CHAT_LOGIN_LOCKOUT", 15*time.Minute),
+		LoginDelayStep:       getEnvDuration("CHAT_LOGIN_DELAY_STEP", 500*time.Millisecond),
+		LoginMaxDelay:        getEnvDuration("CHAT_LOGIN_MAX_DELAY", 8*time.Second),
+
+		AuthBackend: strings.ToLower(getEnv("CHAT_AUTH_BACKEND", "mysql")),
+		AuthFile:    getEnv("CHAT_AUTH_FILE", "users.htpasswd"),
+
+		DuplicateLogin: strings.ToLower(getEnv("CHAT_DUPLICATE_LOGIN", "multi")),
+
+		BcryptCost: getEnvInt("CHAT_BCRYPT_COST", 10),
+
+		PasswordMinLength:      getEnvInt("CHAT_PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses:     getEnvInt("CHAT_PASSWORD_MIN_CLASSES", 2),
+		PasswordRejectUsername: getEnvBool("CHAT_PASSWORD_REJECT_USERNAME", true),
+
+		LogLevel:  strings.ToLower(getEnv("CHAT_LOG_LEVEL", "info")),
+		LogFormat: strings.ToLower(getEnv("CHAT_LOG_FORMAT", "text")),
+	}
+}
+
+// MySQLDSN 返回 MySQL 连接串
+func (c *Config) MySQLDSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&loc=Local",
+		c.MySQLUser, c.MySQLPassword, net.JoinHostPort(c.MySQLHost, c.MySQLPort), c.MySQLDatabase)
+}
+
+// getEnv 读取字符串类型的环境变量，未设置时返回默认值
+func getEnv(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return strings.TrimSpace(value)
+	}
+	return def
+}
+
+// getEnvInt 读取整数类型的环境变量，未设置或格式错误时返回默认值
+func getEnvInt(key string, def int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// getEnvBool 读取布尔类型的环境变量（如 true/false、1/0），未设置或格式错误时返回默认值
+func getEnvBool(key string, def bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// getEnvDuration 读取时长类型的环境变量（如 30s、72h），未设置或格式错误时返回默认值
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// getEnvList 读取逗号分隔的列表类型环境变量，自动忽略空项
+func getEnvList(key string, def []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// 认证审计事件类型
+const (
+	AuthEventLogin    = "login"    // 登录尝试
+	AuthEventRegister = "register" // 注册
+	AuthEventLockout  = "lockout"  // 密码错误次数过多被锁定
+	AuthEventResume   = "resume"   // 使用会话令牌恢复登录
+	AuthEventUnlock   = "unlock"   // 管理员解除账号锁定
+	AuthEventPasswd   = "passwd"   // 修改密码
+	AuthEventDelete   = "delete"   // 注销账号
+)
+
+// 认证审计事件结果
+const (
+	AuthOutcomeSuccess = "success"
+	AuthOutcomeFailure = "failure"
+)
+
+// AuthEvent 认证审计事件
+type AuthEvent struct {
+	ID       int64     // 自增主键
+	Time     time.Time // 事件发生时间
+	Username string    // 涉及的用户名
+	RemoteIP string    // 客户端 IP
+	Event    string    // 事件类型，见 AuthEvent* 常量
+	Outcome  string    // 事件结果，见 AuthOutcome* 常量
+	Reason   string    // 结果原因，如 "密码错误"
+}
+
+// RecordAuthEvent 写入一条认证审计事件，Time 为零值时使用当前时间
+func (udb *UserDB) RecordAuthEvent(ev *AuthEvent) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("record_auth_event", time.Now())
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	_, err := udb.DB.Exec("INSERT INTO auth_events (created_at,username,remote_ip,event,outcome,reason) VALUES (?,?,?,?,?,?)",
+		ev.Time, ev.Username, ev.RemoteIP, ev.Event, ev.Outcome, ev.Reason)
+	if err != nil {
+		return fmt.Errorf("写入认证审计事件失败：%v", err)
+	}
+	return nil
+}
+
+// QueryAuthEvents 查询最近的认证审计事件
+// 参数 user 为空时查询所有用户，limit 是最多返回的条数
+// 返回值按时间从新到旧排列
+func (udb *UserDB) QueryAuthEvents(user string, limit int) ([]AuthEvent, error) {
+	if udb == nil || udb.DB == nil {
+		return nil, fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("query_auth_events", time.Now())
+
+	query := "SELECT id,created_at,username,remote_ip,event,outcome,reason FROM auth_events"
+	args := []any{}
+	if user != "" {
+		query += " WHERE username = ?"
+		args = append(args, user)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := udb.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询认证审计事件失败：%v", err)
+	}
+	defer rows.Close()
+
+	var events []AuthEvent
+	for rows.Next() {
+		var ev AuthEvent
+		if err := rows.Scan(&ev.ID, &ev.Time, &ev.Username, &ev.RemoteIP, &ev.Event, &ev.Outcome, &ev.Reason); err != nil {
+			return nil, fmt.Errorf("读取认证审计事件失败：%v", err)
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取认证审计事件失败：%v", err)
+	}
+	return events, nil
+}
@@ -1,37 +1,66 @@
 package db
 
 import (
+	"GoWork_4/chat_server/nickname"
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"log/slog"
+	"time"
 )
 
+// ErrNameConflict 注册的用户名已存在，或与已注册用户名的骨架相同
+// 由 users 表上用户名和昵称骨架的唯一索引保证，并发注册时同样有效
+var ErrNameConflict = errors.New("用户名已被注册或与已注册用户名过于相似")
+
+// mysqlErrDupEntry MySQL 唯一索引冲突的错误码
+const mysqlErrDupEntry = 1062
+
+// isDuplicateKey 判断错误是否为唯一索引冲突
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry
+}
+
 type UserDB struct {
-	DB *sql.DB
+	DB            *sql.DB
+	QueryObserver func(op string, d time.Duration) // 查询耗时观察函数，用于统计查询延迟，可为 nil
+	BcryptCost    int                              // 密码哈希的 bcrypt 代价，0 表示使用默认值
 }
 
-func ConnectDB() *UserDB {
-	connStr := "root:231792@tcp(localhost:3306)/User"
-	db, err := sql.Open("mysql", connStr)
+// ConnectDB 连接 MySQL 数据库
+// 参数 dsn 是 go-sql-driver/mysql 格式的连接串，需包含 parseTime=true
+// 连接失败时返回 nil
+func ConnectDB(dsn string) *UserDB {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		err = fmt.Errorf("数据库打开失败：%v\n", err)
+		slog.Error("数据库打开失败", "error", err)
 		return nil
 	}
 	if err := db.Ping(); err != nil {
-		fmt.Printf("数据库连接失败：%v\n", err)
+		slog.Error("数据库连接失败", "error", err)
 		db.Close()
 		return nil
 	}
-	fmt.Println("数据库连接成功")
-	return &UserDB{DB: db}
+	slog.Info("数据库连接成功")
+	return &UserDB{DB: db, BcryptCost: DefaultBcryptCost}
 
 }
 
+// observe 在查询结束时上报耗时，用法：defer udb.observe("操作名", time.Now())
+func (udb *UserDB) observe(op string, start time.Time) {
+	if udb.QueryObserver != nil {
+		udb.QueryObserver(op, time.Since(start))
+	}
+}
+
 // CheckNameExists 检查用户名是否已在数据库中存在。
 func (udb *UserDB) CheckNameExists(name string) (bool, error) {
-	if udb.DB == nil {
+	if udb == nil || udb.DB == nil {
 		return false, fmt.Errorf("数据库连接不可用")
 	}
+	defer udb.observe("check_name_exists", time.Now())
 
 	var count int
 	// 假设您的表名为 'users'，字段名为 'username'
@@ -44,32 +73,102 @@ func (udb *UserDB) CheckNameExists(name string) (bool, error) {
 	return count > 0, nil
 }
 
+// FindConfusableUser 查找与 name 骨架相同（仅大小写或形近字符不同）的已注册用户名
+// 返回值：（相似的用户名，是否存在，错误信息）
+func (udb *UserDB) FindConfusableUser(name string) (string, bool, error) {
+	if udb == nil || udb.DB == nil {
+		return "", false, fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("find_confusable_user", time.Now())
+	var existing string
+	err := udb.DB.QueryRow("SELECT username FROM users WHERE username_skeleton = ? LIMIT 1", nickname.Skeleton(name)).Scan(&existing)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("查询相似用户名失败：%v", err)
+	}
+	return existing, true, nil
+}
+
+// BackfillNameSkeletons 为缺少昵称骨架的用户补全骨架
+// 迁移只能新增列，已有用户的骨架需要用与注册相同的算法计算，服务器启动时调用
+// 骨架与其他用户冲突的用户跳过，返回值为补全的用户数
+func (udb *UserDB) BackfillNameSkeletons() (int, error) {
+	if udb == nil || udb.DB == nil {
+		return 0, fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("backfill_name_skeletons", time.Now())
+	rows, err := udb.DB.Query("SELECT username FROM users WHERE username_skeleton IS NULL")
+	if err != nil {
+		return 0, fmt.Errorf("查询缺少骨架的用户失败：%v", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("读取用户名失败：%v", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("读取用户名失败：%v", err)
+	}
+
+	filled := 0
+	for _, name := range names {
+		_, err := udb.DB.Exec("UPDATE users SET username_skeleton = ? WHERE username = ?", nickname.Skeleton(name), name)
+		if isDuplicateKey(err) {
+			// 与更早注册的用户骨架相同，骨架保持为空，下次启动时重试
+			slog.Warn("昵称骨架与已有用户冲突，暂不补全", "user", name)
+			continue
+		}
+		if err != nil {
+			return filled, fmt.Errorf("更新用户 '%s' 的骨架失败：%v", name, err)
+		}
+		filled++
+	}
+	return filled, nil
+}
+
 // RegisterUser 将新用户（昵称和密码）写入数据库（注册功能）。
-// 它会对密码进行哈希处理后存储
+// 它会对密码进行哈希处理后存储，同时保存昵称骨架用于易混淆检测
+// 用户名或骨架与已有用户冲突时返回包装了 ErrNameConflict 的错误
 func (udb *UserDB) RegisterUser(name, password string) error {
-	if udb.DB == nil {
-		return fmt.Errorf("数据库来连接不可用")
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	hash, err := HashPassword(password, udb.BcryptCost)
+	if err != nil {
+		return err
 	}
-	stmt, err := udb.DB.Prepare("INSERT INTO users (username,password_hash,created_at) value (?,?,NOW())")
+	defer udb.observe("register_user", time.Now())
+	stmt, err := udb.DB.Prepare("INSERT INTO users (username,username_skeleton,password_hash,created_at) value (?,?,?,NOW())")
 	if err != nil {
 		return fmt.Errorf("准备SQL语句失败：%v", err)
 	}
 	defer stmt.Close()
-	_, err = stmt.Exec(name, password)
+	_, err = stmt.Exec(name, nickname.Skeleton(name), hash)
+	if isDuplicateKey(err) {
+		return fmt.Errorf("注册用户 '%s' 失败：%w", name, ErrNameConflict)
+	}
 	if err != nil {
 		return fmt.Errorf("执行插入操作失败：%v", err)
 	}
-	fmt.Printf("用户注册成功：\n")
+	slog.Info("用户注册成功", "user", name)
 	return nil
 }
 
 // CheckCredentials 检查用户名和密码是否匹配
-// 流程：从数据库
+// 流程：从数据库读取存储的哈希并校验，旧版明文记录或代价与配置不一致的哈希在校验成功后重新计算并写回
 // 返回值：（是否验证成功，错误信息）
 func (udb *UserDB) CheckCredentials(name, password string) (bool, error) {
-	if udb.DB == nil {
+	if udb == nil || udb.DB == nil {
 		return false, fmt.Errorf("数据库连接不可用")
 	}
+	defer udb.observe("check_credentials", time.Now())
 	var storedPassword string
 	err := udb.DB.QueryRow("select password_hash from users where	username = ?", name).Scan(&storedPassword)
 	if err == sql.ErrNoRows {
@@ -79,18 +178,79 @@ func (udb *UserDB) CheckCredentials(name, password string) (bool, error) {
 		return false, fmt.Errorf("查询用户凭证失败：%v", err)
 	}
 
-	if password == storedPassword {
-		return true, nil
-	} else {
+	ok, needsRehash := verifyPassword(storedPassword, password, udb.BcryptCost)
+	if !ok {
 		return false, nil
 	}
+	if needsRehash {
+		if err := udb.rehashPassword(name, storedPassword, password); err != nil {
+			// 升级失败不影响本次登录，下次登录时会再次尝试
+			slog.Warn("升级密码哈希失败", "user", name, "error", err)
+		}
+	}
+	return true, nil
+}
 
+// rehashPassword 以当前配置的代价重新计算密码哈希并写回
+// 仅当存储值仍为 oldStored 时才更新，避免覆盖并发修改的密码
+func (udb *UserDB) rehashPassword(name, oldStored, password string) error {
+	hash, err := HashPassword(password, udb.BcryptCost)
+	if err != nil {
+		return err
+	}
+	defer udb.observe("rehash_password", time.Now())
+	_, err = udb.DB.Exec("UPDATE users SET password_hash = ? WHERE username = ? AND password_hash = ?", hash, name, oldStored)
+	if err != nil {
+		return fmt.Errorf("更新密码哈希失败：%v", err)
+	}
+	slog.Info("已升级密码哈希", "user", name)
+	return nil
 }
 
 // Close 关闭数据库连接
 func (udb *UserDB) Close() {
-	if udb.DB != nil {
+	if udb != nil && udb.DB != nil {
 		udb.DB.Close()
-		fmt.Println("[DB] 数据库连接已关闭。")
+		slog.Info("数据库连接已关闭")
+	}
+}
+
+// UpdatePassword 将用户密码更新为新密码的哈希
+// 返回值：用户不存在时返回错误
+func (udb *UserDB) UpdatePassword(name, newPassword string) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	hash, err := HashPassword(newPassword, udb.BcryptCost)
+	if err != nil {
+		return err
+	}
+	defer udb.observe("update_password", time.Now())
+	result, err := udb.DB.Exec("UPDATE users SET password_hash = ? WHERE username = ?", hash, name)
+	if err != nil {
+		return fmt.Errorf("更新密码失败：%v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("用户 '%s' 不存在", name)
+	}
+	slog.Info("用户密码已更新", "user", name)
+	return nil
+}
+
+// DeleteUser 删除用户账号，资料由 DeleteProfile 单独删除
+// 认证审计记录和聊天归档保留，以便事后追溯
+func (udb *UserDB) DeleteUser(name string) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("delete_user", time.Now())
+	result, err := udb.DB.Exec("DELETE FROM users WHERE username = ?", name)
+	if err != nil {
+		return fmt.Errorf("删除用户失败：%v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("用户 '%s' 不存在", name)
 	}
+	slog.Info("用户账号已删除", "user", name)
+	return nil
 }
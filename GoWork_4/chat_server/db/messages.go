@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// ArchivedMessage 归档到 MySQL 的聊天消息
+type ArchivedMessage struct {
+	StreamID string    // Redis Stream 消息 ID，用于去重
+	Sender   string    // 发送者昵称
+	Content  string    // 消息内容
+	SentAt   time.Time // 发送时间
+}
+
+// ArchiveMessage 将一条聊天消息写入归档表
+// 以 stream_id 唯一键去重，重复投递的消息会被忽略
+func (udb *UserDB) ArchiveMessage(msg *ArchivedMessage) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("archive_message", time.Now())
+	_, err := udb.DB.Exec("INSERT IGNORE INTO messages (stream_id,sender,content,sent_at) VALUES (?,?,?,?)",
+		msg.StreamID, msg.Sender, msg.Content, msg.SentAt)
+	if err != nil {
+		return fmt.Errorf("归档聊天消息失败：%v", err)
+	}
+	return nil
+}
+
+// SearchMessages 使用全文索引搜索归档消息
+// 参数 keywords 是搜索关键字，limit 是最多返回的条数
+// 返回值按相关度排序
+func (udb *UserDB) SearchMessages(keywords string, limit int) ([]ArchivedMessage, error) {
+	if udb == nil || udb.DB == nil {
+		return nil, fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("search_messages", time.Now())
+	rows, err := udb.DB.Query(`SELECT stream_id,sender,content,sent_at FROM messages
+		WHERE MATCH(content) AGAINST(? IN NATURAL LANGUAGE MODE) LIMIT ?`, keywords, limit)
+	if err != nil {
+		return nil, fmt.Errorf("搜索聊天记录失败：%v", err)
+	}
+	defer rows.Close()
+
+	var results []ArchivedMessage
+	for rows.Next() {
+		var msg ArchivedMessage
+		if err := rows.Scan(&msg.StreamID, &msg.Sender, &msg.Content, &msg.SentAt); err != nil {
+			return nil, fmt.Errorf("读取搜索结果失败：%v", err)
+		}
+		results = append(results, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取搜索结果失败：%v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationLockName 迁移使用的 MySQL 命名锁，多个实例同时启动时只有一个执行迁移
+const migrationLockName = "chat_schema_migrations"
+
+// migrationLockTimeout 等待迁移锁的最长时间（秒）
+const migrationLockTimeout = 60
+
+// migrationFiles 内嵌的迁移脚本，文件名格式为 <版本号>_<名称>.up.sql / .down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration 一个带版本号的数据库迁移
+type Migration struct {
+	Version int    // 版本号，按升序应用
+	Name    string // 迁移名称，如 create_users
+	Up      string // 升级脚本
+	Down    string // 回滚脚本
+}
+
+// MigrationState 迁移的应用状态
+type MigrationState struct {
+	Migration
+	Applied   bool      // 是否已应用
+	AppliedAt time.Time // 应用时间，未应用时为零值
+}
+
+// LoadMigrations 读取内嵌的迁移脚本，按版本号升序返回
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移脚本失败：%v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("迁移文件名格式错误：%s", file)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("迁移文件名格式错误：%s", file)
+		}
+		content, err := migrationFiles.ReadFile("migrations/" + file)
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移脚本 %s 失败：%v", file, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("迁移版本 %d 存在多个名称：%s、%s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("迁移 %04d_%s 缺少 up 脚本", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ensureMigrationsTable 创建迁移记录表（如不存在）
+func (udb *UserDB) ensureMigrationsTable() error {
+	_, err := udb.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at DATETIME NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return fmt.Errorf("创建迁移记录表失败：%v", err)
+	}
+	return nil
+}
+
+// appliedMigrations 返回已应用的迁移版本及其应用时间
+func (udb *UserDB) appliedMigrations() (map[int]time.Time, error) {
+	rows, err := udb.DB.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败：%v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("读取迁移记录失败：%v", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败：%v", err)
+	}
+	return applied, nil
+}
+
+// MigrationStatus 返回所有迁移及其应用状态
+func (udb *UserDB) MigrationStatus() ([]MigrationState, error) {
+	if udb == nil || udb.DB == nil {
+		return nil, fmt.Errorf("数据库连接不可用")
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := udb.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	applied, err := udb.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		states = append(states, MigrationState{Migration: m, Applied: ok, AppliedAt: appliedAt})
+	}
+	return states, nil
+}
+
+// withMigrationLock 持有迁移锁执行 fn，避免多个实例并发应用或回滚迁移
+// MySQL 命名锁属于会话，因此加锁和解锁使用同一个专用连接
+func (udb *UserDB) withMigrationLock(fn func() error) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	ctx := context.Background()
+	conn, err := udb.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败：%v", err)
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&got); err != nil {
+		return fmt.Errorf("获取迁移锁失败：%v", err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return fmt.Errorf("等待迁移锁超时（%d秒），可能有其他实例正在执行迁移", migrationLockTimeout)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName); err != nil {
+			slog.Warn("释放迁移锁失败", "lock", migrationLockName, "error", err)
+		}
+	}()
+	return fn()
+}
+
+// MigrateUp 按版本号顺序应用所有未应用的迁移
+// 返回值为本次应用的迁移列表
+func (udb *UserDB) MigrateUp() ([]Migration, error) {
+	var done []Migration
+	err := udb.withMigrationLock(func() error {
+		var err error
+		done, err = udb.migrateUp()
+		return err
+	})
+	return done, err
+}
+
+// migrateUp 在持有迁移锁的情况下应用未应用的迁移
+// 加锁前其他实例可能已经完成迁移，因此在锁内重新读取迁移状态
+func (udb *UserDB) migrateUp() ([]Migration, error) {
+	states, err := udb.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, st := range states {
+		if st.Applied {
+			continue
+		}
+		if err := udb.execScript(st.Up); err != nil {
+			return done, fmt.Errorf("应用迁移 %04d_%s 失败：%v", st.Version, st.Name, err)
+		}
+		if _, err := udb.DB.Exec("INSERT INTO schema_migrations (version,name,applied_at) VALUES (?,?,?)",
+			st.Version, st.Name, time.Now()); err != nil {
+			return done, fmt.Errorf("记录迁移 %04d_%s 失败：%v", st.Version, st.Name, err)
+		}
+		slog.Info("已应用数据库迁移", "version", st.Version, "name", st.Name)
+		done = append(done, st.Migration)
+	}
+	return done, nil
+}
+
+// MigrateDown 按版本号倒序回滚最近应用的 steps 个迁移
+// 返回值为本次回滚的迁移列表
+func (udb *UserDB) MigrateDown(steps int) ([]Migration, error) {
+	var done []Migration
+	err := udb.withMigrationLock(func() error {
+		var err error
+		done, err = udb.migrateDown(steps)
+		return err
+	})
+	return done, err
+}
+
+// migrateDown 在持有迁移锁的情况下回滚迁移
+func (udb *UserDB) migrateDown(steps int) ([]Migration, error) {
+	states, err := udb.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		st := states[i]
+		if !st.Applied {
+			continue
+		}
+		if st.Down == "" {
+			return done, fmt.Errorf("迁移 %04d_%s 没有 down 脚本，不支持回滚", st.Version, st.Name)
+		}
+		if err := udb.execScript(st.Down); err != nil {
+			return done, fmt.Errorf("回滚迁移 %04d_%s 失败：%v", st.Version, st.Name, err)
+		}
+		if _, err := udb.DB.Exec("DELETE FROM schema_migrations WHERE version = ?", st.Version); err != nil {
+			return done, fmt.Errorf("删除迁移记录 %04d_%s 失败：%v", st.Version, st.Name, err)
+		}
+		slog.Info("已回滚数据库迁移", "version", st.Version, "name", st.Name)
+		done = append(done, st.Migration)
+	}
+	return done, nil
+}
+
+// execScript 逐条执行迁移脚本中的 SQL 语句
+// MySQL 驱动默认不支持一次执行多条语句，这里按行尾的分号拆分
+func (udb *UserDB) execScript(script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := udb.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 将脚本按行尾分号拆分为多条语句，并去掉 -- 开头的注释行
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,10 @@
+-- 用户表；使用 IF NOT EXISTS 以便接管迁移系统引入前手工创建的表
+-- 不提供 down 脚本：被接管的表中保存着已有账号，回滚时不能删除
+-- username 按二进制比较，登录时必须输入与注册时完全相同的昵称；仅大小写不同的昵称由骨架唯一索引拒绝注册
+CREATE TABLE IF NOT EXISTS users (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE KEY uk_users_username (username)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+-- 聊天归档表；content 使用 ngram 解析器建立全文索引，以支持中文关键字搜索
+CREATE TABLE IF NOT EXISTS messages (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    stream_id VARCHAR(64) NOT NULL,
+    sender VARCHAR(64) NOT NULL,
+    content TEXT NOT NULL,
+    sent_at DATETIME NOT NULL,
+    UNIQUE KEY uk_messages_stream_id (stream_id),
+    KEY idx_messages_sent_at (sent_at),
+    FULLTEXT KEY ft_messages_content (content) WITH PARSER ngram
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,12 @@
+-- 认证审计表
+CREATE TABLE IF NOT EXISTS auth_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    created_at DATETIME NOT NULL,
+    username VARCHAR(64) NOT NULL,
+    remote_ip VARCHAR(64) NOT NULL,
+    event VARCHAR(16) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    KEY idx_auth_events_username (username, created_at),
+    KEY idx_auth_events_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+DROP INDEX idx_users_username_skeleton ON users;
+ALTER TABLE users DROP COLUMN username_skeleton;
@@ -0,0 +1,4 @@
+-- 用户名骨架：归一化大小写和易混淆字符后的形式，用于拒绝与已有用户名相似的昵称
+-- 已有用户的骨架由服务器启动时回填
+ALTER TABLE users ADD COLUMN username_skeleton VARCHAR(255) NULL AFTER username;
+CREATE INDEX idx_users_username_skeleton ON users (username_skeleton);
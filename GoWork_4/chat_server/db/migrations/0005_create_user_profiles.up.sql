@@ -0,0 +1,9 @@
+-- 用户资料表；与 users 分开存放，未设置过资料的用户没有对应记录
+CREATE TABLE IF NOT EXISTS user_profiles (
+    username VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL PRIMARY KEY,
+    display_name VARCHAR(64) NOT NULL DEFAULT '',
+    bio VARCHAR(255) NOT NULL DEFAULT '',
+    pronouns VARCHAR(32) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
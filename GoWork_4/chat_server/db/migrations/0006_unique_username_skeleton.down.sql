@@ -0,0 +1,2 @@
+DROP INDEX uk_users_username_skeleton ON users;
+CREATE INDEX idx_users_username_skeleton ON users (username_skeleton);
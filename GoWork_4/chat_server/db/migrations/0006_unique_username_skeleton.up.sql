@@ -0,0 +1,20 @@
+-- 昵称骨架改为唯一索引，防止并发注册两个易混淆的昵称
+-- 已有的冲突骨架只保留最早注册的用户（注册时间相同时取用户名最小者），其余置空（MySQL 唯一索引允许多个 NULL），
+-- 置空的骨架由服务器启动时的回填重新尝试，冲突的用户被删除后即可补全
+-- 不使用 id 列：0001 接管的手工建表不一定有该列
+UPDATE users u
+JOIN (
+    SELECT s.username_skeleton, MIN(s.username) AS keep_name
+    FROM users s
+    JOIN (
+        SELECT username_skeleton, MIN(created_at) AS first_at
+        FROM users
+        WHERE username_skeleton IS NOT NULL
+        GROUP BY username_skeleton
+        HAVING COUNT(*) > 1
+    ) earliest ON s.username_skeleton = earliest.username_skeleton AND s.created_at = earliest.first_at
+    GROUP BY s.username_skeleton
+) dup ON u.username_skeleton = dup.username_skeleton AND u.username <> dup.keep_name
+SET u.username_skeleton = NULL;
+DROP INDEX idx_users_username_skeleton ON users;
+CREATE UNIQUE INDEX uk_users_username_skeleton ON users (username_skeleton);
@@ -0,0 +1,2 @@
+ALTER TABLE user_profiles MODIFY username VARCHAR(64) CHARACTER SET utf8mb4 NOT NULL;
+ALTER TABLE users MODIFY username VARCHAR(64) CHARACTER SET utf8mb4 NOT NULL;
@@ -0,0 +1,5 @@
+-- 用户名改为按二进制比较
+-- 默认排序规则不区分大小写，输入 BOB 也能登录账号 bob，同一账号会以多个昵称同时在线
+-- 二进制比较比原排序规则更严格，修改后不会产生新的唯一索引冲突
+ALTER TABLE users MODIFY username VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
+ALTER TABLE user_profiles MODIFY username VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL;
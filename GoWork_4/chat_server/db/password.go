@@ -0,0 +1,52 @@
+package db
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost 默认的 bcrypt 计算代价
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+// MaxPasswordBytes bcrypt 能处理的最大密码字节数，超出部分会被拒绝
+const MaxPasswordBytes = 72
+
+// HashPassword 使用 bcrypt 计算密码哈希
+// 参数 cost 超出 bcrypt 允许范围时使用默认代价
+func HashPassword(password string, cost int) (string, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("计算密码哈希失败：%v", err)
+	}
+	return string(hash), nil
+}
+
+// isBcryptHash 判断数据库中存储的值是否为 bcrypt 哈希
+// 早期版本直接存储明文密码，这些记录在用户下次登录成功时升级
+func isBcryptHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+// verifyPassword 校验密码是否与存储值匹配
+// 返回值：（是否匹配，是否需要以当前代价重新计算哈希）
+func verifyPassword(stored, password string, cost int) (bool, bool) {
+	if !isBcryptHash(stored) {
+		// 旧版明文记录：使用常量时间比较，匹配后升级为哈希
+		ok := subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+		return ok, ok
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)); err != nil {
+		return false, false
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+	storedCost, err := bcrypt.Cost([]byte(stored))
+	return true, err == nil && storedCost != cost
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 用户资料字段，取值即 user_profiles 表的列名
+const (
+	ProfileDisplayName = "display_name" // 显示名称
+	ProfileBio         = "bio"          // 个人简介
+	ProfilePronouns    = "pronouns"     // 代词
+	ProfileTimeZone    = "timezone"     // 时区（IANA 名称，如 Asia/Shanghai）
+)
+
+// profileColumns 允许通过 SetProfileField 修改的列，防止拼接任意列名
+var profileColumns = map[string]bool{
+	ProfileDisplayName: true,
+	ProfileBio:         true,
+	ProfilePronouns:    true,
+	ProfileTimeZone:    true,
+}
+
+// UserProfile 用户资料
+type UserProfile struct {
+	Username     string    // 登录用户名
+	DisplayName  string    // 显示名称，为空表示未设置
+	Bio          string    // 个人简介
+	Pronouns     string    // 代词
+	TimeZone     string    // 时区
+	RegisteredAt time.Time // 注册时间，来自 users 表，未知时为零值
+}
+
+// GetProfile 查询用户资料，未设置过资料的字段为空
+// 账号不在 MySQL 中（使用文件或内存账号后端）时 RegisteredAt 为零值
+func (udb *UserDB) GetProfile(name string) (*UserProfile, error) {
+	if udb == nil || udb.DB == nil {
+		return nil, fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("get_profile", time.Now())
+	p := &UserProfile{Username: name}
+	err := udb.DB.QueryRow("SELECT display_name, bio, pronouns, timezone FROM user_profiles WHERE username = ?", name).
+		Scan(&p.DisplayName, &p.Bio, &p.Pronouns, &p.TimeZone)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("查询用户资料失败：%v", err)
+	}
+	err = udb.DB.QueryRow("SELECT created_at FROM users WHERE username = ?", name).Scan(&p.RegisteredAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("查询注册时间失败：%v", err)
+	}
+	return p, nil
+}
+
+// SetProfileField 设置用户资料中的单个字段，value 为空表示清除该字段
+// 参数 field 取值见 Profile* 常量
+func (udb *UserDB) SetProfileField(name, field, value string) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	if !profileColumns[field] {
+		return fmt.Errorf("未知的资料字段：%s", field)
+	}
+	defer udb.observe("set_profile_field", time.Now())
+	// field 已经过白名单校验，可以安全地拼接到语句中
+	query := fmt.Sprintf("INSERT INTO user_profiles (username,%[1]s,updated_at) VALUES (?,?,NOW()) "+
+		"ON DUPLICATE KEY UPDATE %[1]s = VALUES(%[1]s), updated_at = NOW()", field)
+	if _, err := udb.DB.Exec(query, name, value); err != nil {
+		return fmt.Errorf("更新用户资料失败：%v", err)
+	}
+	return nil
+}
+
+// DeleteProfile 删除用户资料，用户没有资料时不报错
+// 账号可能存储在 MySQL 之外，因此资料与账号分开删除
+func (udb *UserDB) DeleteProfile(name string) error {
+	if udb == nil || udb.DB == nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+	defer udb.observe("delete_profile", time.Now())
+	if _, err := udb.DB.Exec("DELETE FROM user_profiles WHERE username = ?", name); err != nil {
+		return fmt.Errorf("删除用户资料失败：%v", err)
+	}
+	return nil
+}
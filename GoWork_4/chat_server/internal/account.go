@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+)
+
+// deleteAccountConfirm 注销账号时需要用户输入的确认文本
+const deleteAccountConfirm = "YES"
+
+// cmdPasswd 处理 /passwd 命令，校验当前密码后设置新密码
+// 修改成功后吊销该用户的所有会话令牌，并为当前连接重新签发令牌
+func (s *Server) cmdPasswd(ctx *CommandContext) {
+	if !s.confirmPassword(ctx, db.AuthEventPasswd) {
+		return
+	}
+
+	newPassword, err := ctx.PromptPassword(fmt.Sprintf("请输入新密码（%s，直接回车取消）：", s.passwordPolicy.Describe()))
+	if err != nil {
+		return
+	}
+	if newPassword == "" {
+		ctx.Reply("系统：已取消修改密码")
+		return
+	}
+	reason, disconnected := s.confirmNewPassword(ctx.Conn, ctx.Name, newPassword, tools.FormatPasswordPrompt("请再次输入新密码："))
+	if disconnected {
+		return
+	}
+	if reason != "" {
+		s.recordAuthEvent(ctx.Conn, db.AuthEventPasswd, ctx.Name, db.AuthOutcomeFailure, reason)
+		ctx.Reply(fmt.Sprintf("系统：%s，密码未修改", reason))
+		return
+	}
+	if err := s.users.UpdatePassword(ctx.Name, newPassword); err != nil {
+		slog.Error("修改密码失败", "user", ctx.Name, "error", err)
+		s.recordAuthEvent(ctx.Conn, db.AuthEventPasswd, ctx.Name, db.AuthOutcomeFailure, "数据库写入错误")
+		ctx.Reply("系统：修改密码失败，请稍后重试")
+		return
+	}
+	s.recordAuthEvent(ctx.Conn, db.AuthEventPasswd, ctx.Name, db.AuthOutcomeSuccess, "")
+
+	// 旧密码可能已经泄露，使其他设备上的令牌全部失效，当前连接换发新令牌
+	s.revokeUserSessions(ctx.Name)
+	s.issueSession(ctx.Conn, ctx.Name)
+	ctx.Reply("系统：密码修改成功，其他设备需要使用新密码重新登录")
+}
+
+// cmdDeleteAccount 处理 /deleteaccount 命令，校验密码并二次确认后注销账号
+// 注销会删除账号、资料和活跃度排名，吊销所有会话令牌并断开该用户的连接
+func (s *Server) cmdDeleteAccount(ctx *CommandContext) {
+	if !s.confirmPassword(ctx, db.AuthEventDelete) {
+		return
+	}
+
+	answer, err := ctx.Prompt(fmt.Sprintf("注销后账号 '%s' 和活跃度排名将被永久删除，无法恢复。输入 %s 确认注销：", ctx.Name, deleteAccountConfirm))
+	if err != nil {
+		return
+	}
+	if answer != deleteAccountConfirm {
+		ctx.Reply("系统：已取消注销账号")
+		return
+	}
+
+	if err := s.users.Delete(ctx.Name); err != nil {
+		slog.Error("注销账号失败", "user", ctx.Name, "error", err)
+		s.recordAuthEvent(ctx.Conn, db.AuthEventDelete, ctx.Name, db.AuthOutcomeFailure, "数据库写入错误")
+		ctx.Reply("系统：注销账号失败，请稍后重试")
+		return
+	}
+	s.recordAuthEvent(ctx.Conn, db.AuthEventDelete, ctx.Name, db.AuthOutcomeSuccess, "")
+
+	// 资料总是存储在 MySQL 中，与账号存储后端无关；不删除的话，之后注册同名账号的用户会继承这些资料
+	if s.userDB != nil {
+		if err := s.userDB.DeleteProfile(ctx.Name); err != nil {
+			slog.Warn("删除用户资料失败", "user", ctx.Name, "error", err)
+		}
+	}
+	if s.asyncQueue != nil {
+		if err := s.asyncQueue.RemoveUserRank(ctx.Name); err != nil {
+			slog.Warn("移除用户排名失败", "user", ctx.Name, "error", err)
+		}
+	}
+	s.revokeUserSessions(ctx.Name)
+
+	// 同一账号可能在多个连接上登录，全部通知并断开
+	s.disconnectUser(ctx.Name, "系统：账号已注销，再见！")
+}
+
+// confirmPassword 要求用户重新输入当前密码，用于敏感操作前的身份确认
+// 与登录共用失败计数和锁定状态，防止通过已登录的会话无限次猜测密码
+// 参数 event 是校验失败时记录的审计事件类型
+// 返回值表示密码是否正确（失败时已通知客户端）
+func (s *Server) confirmPassword(ctx *CommandContext, event string) bool {
+	if remaining, locked := s.loginLockout(ctx.Conn, ctx.Name); locked {
+		s.recordAuthEvent(ctx.Conn, event, ctx.Name, db.AuthOutcomeFailure, "账号或IP已锁定")
+		ctx.Reply(fmt.Sprintf("系统：密码错误次数过多，账号或IP已被临时锁定，请 %s 后再试", formatLockout(remaining)))
+		return false
+	}
+	password, err := ctx.PromptPassword("请输入当前密码（直接回车取消）：")
+	if err != nil {
+		return false
+	}
+	if password == "" {
+		ctx.Reply("系统：操作已取消")
+		return false
+	}
+	ok, err := s.users.Verify(ctx.Name, password)
+	if err != nil {
+		slog.Error("校验当前密码失败", "user", ctx.Name, "error", err)
+		s.recordAuthEvent(ctx.Conn, event, ctx.Name, db.AuthOutcomeFailure, "数据库验证错误")
+		ctx.Reply("系统：密码校验失败，请稍后重试")
+		return false
+	}
+	if !ok {
+		s.recordAuthEvent(ctx.Conn, event, ctx.Name, db.AuthOutcomeFailure, "密码错误")
+		if result := s.countLoginFailure(ctx.Conn, ctx.Name); result != nil && result.Locked {
+			ctx.Reply(fmt.Sprintf("系统：密码不正确。失败次数过多，账号或IP已被临时锁定，请 %s 后再试", formatLockout(result.LockedFor)))
+			return false
+		}
+		ctx.Reply("系统：密码不正确，操作已取消")
+		return false
+	}
+	s.clearLoginFailures(ctx.Name)
+	return true
+}
+
+// revokeUserSessions 吊销用户的所有会话令牌，并清除在线连接上记录的令牌
+func (s *Server) revokeUserSessions(name string) {
+	s.mutex.Lock()
+	for conn, claims := range s.sessions {
+		if claims.User == name {
+			delete(s.sessions, conn)
+		}
+	}
+	s.mutex.Unlock()
+
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		return
+	}
+	n, err := s.asyncQueue.RevokeUserSessions(name)
+	if err != nil {
+		slog.Error("吊销用户会话令牌失败", "user", name, "error", err)
+		return
+	}
+	slog.Info("已吊销用户会话令牌", "user", name, "count", n)
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/rdb"
+	"GoWork_4/tools"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// registerAdminAPI 在 HTTP 路由上注册管理 API，所有接口都需要携带管理令牌
+func (s *Server) registerAdminAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /users", s.requireAdminToken(s.apiListUsers))
+	mux.HandleFunc("POST /broadcast", s.requireAdminToken(s.apiBroadcast))
+	mux.HandleFunc("POST /users/{name}/kick", s.requireAdminToken(s.apiKickUser))
+	mux.HandleFunc("GET /history", s.requireAdminToken(s.apiHistory))
+	mux.HandleFunc("GET /rank", s.requireAdminToken(s.apiRank))
+}
+
+// requireAdminToken 校验请求头中的管理令牌
+// 支持 "Authorization: Bearer <令牌>" 和 "X-Admin-Token: <令牌>" 两种方式
+func (s *Server) requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "管理令牌无效")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON 以 JSON 格式写出响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError 以 JSON 格式写出错误响应
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// apiUser 在线用户信息
+type apiUser struct {
+	Name       string `json:"name"`
+	RemoteAddr string `json:"remote_addr"` // 最近登录的会话地址
+	Bot        bool   `json:"bot"`
+	Sessions   int    `json:"sessions"` // 同时在线的会话数
+}
+
+// apiListUsers 处理 GET /users，返回在线用户列表
+func (s *Server) apiListUsers(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	users := make([]apiUser, 0, len(s.clients))
+	for name, conns := range s.clients {
+		latest := conns[len(conns)-1]
+		users = append(users, apiUser{
+			Name:       name,
+			RemoteAddr: latest.RemoteAddr().String(),
+			Bot:        isBotConn(latest),
+			Sessions:   len(conns),
+		})
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(users), "users": users})
+}
+
+// apiBroadcast 处理 POST /broadcast，以系统公告的形式向所有在线用户广播消息
+// 请求体：{"message": "公告内容"}
+func (s *Server) apiBroadcast(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Message) == "" {
+		writeJSONError(w, http.StatusBadRequest, "请求体应为 {\"message\": \"公告内容\"}")
+		return
+	}
+	s.messageChan <- &ClientMessage{
+		Name:    "[系统]",
+		Message: "【系统公告】" + req.Message,
+		Type:    "system",
+	}
+	writeJSON(w, http.StatusAccepted, map[string]bool{"ok": true})
+}
+
+// apiKickUser 处理 POST /users/{name}/kick，吊销指定用户的会话令牌并断开其所有连接
+// 请求体可选：{"reason": "踢出原因"}
+func (s *Server) apiKickUser(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if !s.isNameTaken(name) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("用户 %s 不在线", name))
+		return
+	}
+	notice := tools.KickNotice
+	if req.Reason != "" {
+		notice += "，原因：" + req.Reason
+	}
+	// 先吊销令牌，否则客户端断开后会凭令牌自动恢复会话
+	s.revokeUserSessions(name)
+	s.disconnectUser(name, notice)
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// apiHistory 处理 GET /history，参数与 /history 命令一致：n、before、after、user、since（RFC3339 或时长）
+func (s *Server) apiHistory(w http.ResponseWriter, r *http.Request) {
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Redis 未连接")
+		return
+	}
+	q := r.URL.Query()
+	query := rdb.HistoryQuery{
+		Count:  10,
+		Before: q.Get("before"),
+		After:  q.Get("after"),
+		User:   q.Get("user"),
+	}
+	if n := q.Get("n"); n != "" {
+		count, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || count <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "参数 n 必须为正整数")
+			return
+		}
+		query.Count = count
+	}
+	if s.historyMaxPage > 0 && query.Count > s.historyMaxPage {
+		query.Count = s.historyMaxPage
+	}
+	if since := q.Get("since"); since != "" {
+		t, err := parseSinceArg(since, time.Now())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		query.Since = t
+	}
+
+	page, err := s.asyncQueue.GetChatHistoryPage(query)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":     page.Entries,
+		"next_cursor": page.NextCursor,
+	})
+}
+
+// apiRank 处理 GET /rank，参数 window（day/week/month/all）和 n
+func (s *Server) apiRank(w http.ResponseWriter, r *http.Request) {
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "Redis 未连接")
+		return
+	}
+	q := r.URL.Query()
+	window := rdb.RankAll
+	if value := q.Get("window"); value != "" {
+		parsed, ok := rdb.ParseRankWindow(value)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "参数 window 只能是 day、week、month 或 all")
+			return
+		}
+		window = parsed
+	}
+	count := int64(10)
+	if n := q.Get("n"); n != "" {
+		c, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || c <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "参数 n 必须为正整数")
+			return
+		}
+		count = min(c, 100)
+	}
+
+	entries, err := s.asyncQueue.GetRankWindow(window, count)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"window":  window,
+		"entries": entries,
+	})
+}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"log/slog"
+	"net"
+)
+
+// recordAuthEvent 记录一条认证审计事件
+// 数据库未连接时跳过，写入失败只记录日志，不影响登录和注册流程
+func (s *Server) recordAuthEvent(conn net.Conn, event, user, outcome, reason string) {
+	if s.userDB == nil {
+		return
+	}
+	ev := &db.AuthEvent{
+		Username: user,
+		RemoteIP: remoteIP(conn),
+		Event:    event,
+		Outcome:  outcome,
+		Reason:   reason,
+	}
+	if err := s.userDB.RecordAuthEvent(ev); err != nil {
+		slog.Error("写入认证审计事件失败", "user", user, "remote_addr", ev.RemoteIP, "event", event, "error", err)
+	}
+}
+
+// remoteIP 返回连接的客户端 IP，不含端口
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
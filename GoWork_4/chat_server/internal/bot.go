@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/config"
+	"GoWork_4/tools"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// BotEvent 机器人收到的消息事件
+type BotEvent struct {
+	Type    string // 消息类型（chat/private/system）
+	From    string // 发送者昵称
+	Message string // 消息内容
+	Target  string // 私聊目标用户（仅 private 类型有效）
+}
+
+// BotReplier 机器人用于回复消息的接口
+// 回复会和普通用户的消息一样进入 messageChan，经过中间件、入队和广播流程。
+type BotReplier interface {
+	Say(message string)             // 在聊天室公开发言
+	Whisper(target, message string) // 私聊指定用户
+}
+
+// Bot 进程内机器人接口
+// 机器人以虚拟用户的身份注册到 Server.clients 中，不需要真实的网络连接。
+type Bot interface {
+	Name() string                           // 机器人在聊天室中的昵称
+	OnEvent(reply BotReplier, ev *BotEvent) // 收到聊天室消息或私聊时被调用
+}
+
+// BotFactory 根据服务器配置构造一个机器人实例
+type BotFactory func(cfg *config.Config) Bot
+
+// botRegistry 保存所有在代码中注册的机器人，键为在配置中引用的名称
+var botRegistry = make(map[string]BotFactory)
+
+// RegisterBot 注册一个可通过配置启用的机器人
+// 参数 name 是在 CHAT_BOTS 中引用的名称，factory 用于构造机器人实例
+func RegisterBot(name string, factory BotFactory) {
+	botRegistry[name] = factory
+}
+
+// buildBots 按配置构造需要启用的机器人，未注册的名称会被忽略并打印警告
+func buildBots(cfg *config.Config) []Bot {
+	var bots []Bot
+	for _, name := range cfg.Bots {
+		factory, ok := botRegistry[name]
+		if !ok {
+			slog.Warn("未知的机器人，已忽略", "bot", name)
+			continue
+		}
+		bots = append(bots, factory(cfg))
+	}
+	return bots
+}
+
+// startBots 将已启用的机器人注册为在线用户并启动事件处理协程
+func (s *Server) startBots() {
+	for _, bot := range s.bots {
+		name := bot.Name()
+		if s.isNameTaken(name) {
+			slog.Warn("机器人昵称已被占用，跳过启动", "bot", name)
+			continue
+		}
+		conn := newBotConn(s, bot)
+		s.registerClient(conn, name)
+		go conn.run()
+		slog.Info("机器人已上线", "bot", name)
+	}
+}
+
+// deliverMessage 将消息投递给指定连接
+// 普通连接发送格式化后的文本，机器人连接则直接投递结构化事件
+func deliverMessage(conn net.Conn, text string, msg *ClientMessage) error {
+	if bc, ok := conn.(*botConn); ok {
+		bc.deliver(msg)
+		return nil
+	}
+	return tools.SendMessage(conn, text)
+}
+
+// isBotConn 判断连接是否属于进程内机器人
+func isBotConn(conn net.Conn) bool {
+	_, ok := conn.(*botConn)
+	return ok
+}
+
+// botConn 机器人的虚拟连接
+// 实现 net.Conn 接口以便存入 Server.clients，写入的数据会被丢弃，
+// 消息通过 deliver 以事件形式交给机器人处理。
+type botConn struct {
+	server    *Server
+	bot       Bot
+	events    chan *BotEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newBotConn 为机器人创建虚拟连接
+func newBotConn(s *Server, bot Bot) *botConn {
+	return &botConn{
+		server: s,
+		bot:    bot,
+		events: make(chan *BotEvent, 100),
+		closed: make(chan struct{}),
+	}
+}
+
+// deliver 将消息转换为事件放入机器人的事件队列，队列已满时丢弃，避免阻塞广播协程
+func (bc *botConn) deliver(msg *ClientMessage) {
+	ev := &BotEvent{
+		Type:    msg.Type,
+		From:    msg.Name,
+		Message: msg.Message,
+		Target:  msg.Target,
+	}
+	select {
+	case bc.events <- ev:
+	case <-bc.closed:
+	default:
+		slog.Warn("机器人事件队列已满，丢弃消息", "bot", bc.bot.Name(), "msg_type", msg.Type)
+	}
+}
+
+// run 机器人事件循环，忽略机器人自己发出的消息
+func (bc *botConn) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("机器人发生 panic", "bot", bc.bot.Name(), "panic", r)
+		}
+	}()
+
+	for {
+		select {
+		case <-bc.closed:
+			return
+		case ev := <-bc.events:
+			if ev.From == bc.bot.Name() {
+				continue
+			}
+			bc.bot.OnEvent(bc, ev)
+		}
+	}
+}
+
+// Say 以机器人身份在聊天室公开发言
+func (bc *botConn) Say(message string) {
+	bc.server.messageChan <- &ClientMessage{
+		Conn:    bc,
+		Name:    bc.bot.Name(),
+		Message: message,
+		Type:    "chat",
+	}
+}
+
+// Whisper 以机器人身份私聊指定用户
+func (bc *botConn) Whisper(target, message string) {
+	if !bc.server.isNameTaken(target) {
+		return
+	}
+	bc.server.messageChan <- &ClientMessage{
+		Conn:    bc,
+		Name:    bc.bot.Name(),
+		Message: message,
+		Type:    "private",
+		Target:  target,
+	}
+}
+
+// Read 机器人连接没有可读数据，阻塞直到连接关闭
+func (bc *botConn) Read(b []byte) (int, error) {
+	<-bc.closed
+	return 0, io.EOF
+}
+
+// Write 丢弃写入的数据，机器人只处理 deliver 投递的事件
+func (bc *botConn) Write(b []byte) (int, error) {
+	select {
+	case <-bc.closed:
+		return 0, net.ErrClosed
+	default:
+		return len(b), nil
+	}
+}
+
+// Close 关闭虚拟连接并停止事件循环
+func (bc *botConn) Close() error {
+	bc.closeOnce.Do(func() {
+		close(bc.closed)
+	})
+	return nil
+}
+
+func (bc *botConn) LocalAddr() net.Addr                { return botAddr(bc.bot.Name()) }
+func (bc *botConn) RemoteAddr() net.Addr               { return botAddr(bc.bot.Name()) }
+func (bc *botConn) SetDeadline(t time.Time) error      { return nil }
+func (bc *botConn) SetReadDeadline(t time.Time) error  { return nil }
+func (bc *botConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// botAddr 机器人虚拟连接的地址
+type botAddr string
+
+func (a botAddr) Network() string { return "bot" }
+func (a botAddr) String() string  { return "bot:" + string(a) }
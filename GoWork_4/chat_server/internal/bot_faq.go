@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/config"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	RegisterBot("faq", newFAQBot)
+}
+
+// faqEntry 常见问题条目
+type faqEntry struct {
+	Keyword string // 触发关键字
+	Answer  string // 回复内容
+}
+
+// faqBot 常见问题机器人
+// 在聊天室或私聊中发送 !help 可查看所有关键字，发送关键字即可得到对应解答。
+type faqBot struct {
+	name    string
+	entries []faqEntry
+}
+
+// newFAQBot 创建常见问题机器人
+func newFAQBot(cfg *config.Config) Bot {
+	return &faqBot{
+		name: "FAQBot",
+		entries: []faqEntry{
+			{Keyword: "!private", Answer: "使用 @用户名 消息内容 发送私聊消息，例如: @张三 你好！"},
+			{Keyword: "!history", Answer: "使用 /history 或 /h 查看最近的聊天历史记录"},
+			{Keyword: "!rank", Answer: "使用 /rank 查看活跃度排名"},
+			{Keyword: "!list", Answer: "使用 /list 查看当前在线用户"},
+		},
+	}
+}
+
+// Name 返回机器人昵称
+func (b *faqBot) Name() string {
+	return b.name
+}
+
+// OnEvent 响应以 ! 开头的关键字，公开提问公开回复，私聊提问私聊回复
+func (b *faqBot) OnEvent(reply BotReplier, ev *BotEvent) {
+	if ev.Type != "chat" && ev.Type != "private" {
+		return
+	}
+	keyword := strings.TrimSpace(ev.Message)
+	if !strings.HasPrefix(keyword, "!") {
+		return
+	}
+
+	answer, ok := b.answer(keyword)
+	if !ok {
+		return
+	}
+	if ev.Type == "private" {
+		reply.Whisper(ev.From, answer)
+	} else {
+		reply.Say(fmt.Sprintf("@%s %s", ev.From, answer))
+	}
+}
+
+// answer 根据关键字查找解答，!help 返回所有可用关键字
+func (b *faqBot) answer(keyword string) (string, bool) {
+	if keyword == "!help" {
+		var keywords []string
+		for _, entry := range b.entries {
+			keywords = append(keywords, entry.Keyword)
+		}
+		return "可用关键字: " + strings.Join(keywords, ", "), true
+	}
+	for _, entry := range b.entries {
+		if entry.Keyword == keyword {
+			return entry.Answer, true
+		}
+	}
+	return "", false
+}
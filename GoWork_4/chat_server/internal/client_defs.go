@@ -1,96 +1,157 @@
 package internal
 
 import (
+	"GoWork_4/chat_server/auth"
+	"GoWork_4/chat_server/config"
 	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/nickname"
 	"GoWork_4/chat_server/rdb"
-	"context"
-	"fmt"
+	"GoWork_4/chat_server/session"
+	"GoWork_4/chat_server/stats"
+	"log/slog"
 	"net"
-	"strings"
+	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ClientMessage 客户端消息结构
 // 用于封装客户端发送的消息信息，包括连接、名称、消息内容等字段。
 type ClientMessage struct {
-	Conn    net.Conn // 客户端网络连接
-	Name    string   // 用户名
-	Message string   // 消息内容
-	Type    string   // 消息类型（如 chat/system）
-	Target  string   // 私聊目标用户
+	Conn    net.Conn          // 客户端网络连接
+	Name    string            // 用户名
+	Message string            // 消息内容
+	Type    string            // 消息类型（如 chat/system）
+	Target  string            // 私聊目标用户
+	Meta    map[string]string // 中间件附加的注解信息
+	ID      string            // Redis Stream 消息 ID，仅经由 Stream 广播的聊天消息才有
 }
 
 // Server 服务器结构
 // 包含所有客户端连接管理、消息处理通道及同步控制组件。
 type Server struct {
-	clients          map[string]net.Conn // 存储用户名到连接的映射
-	clientConnToName map[net.Conn]string // 存储连接到用户名的映射
-	mutex            sync.RWMutex        // 读写锁保护并发访问
-	messageChan      chan *ClientMessage // 接收普通消息的通道
-	broadcastChan    chan *ClientMessage // 广播消息通道
-	registerChan     chan net.Conn       // 注册新客户端连接的通道
-	unregisterChan   chan net.Conn       // 取消注册客户端连接的通道
-	Done             chan struct{}       // 控制服务停止的信号通道
+	clients          map[string][]net.Conn  // 存储用户名到该用户所有已登录连接的映射（按登录顺序）
+	clientConnToName map[net.Conn]string    // 存储连接到用户名的映射
+	connectedAt      map[net.Conn]time.Time // 已登录连接的上线时间
+	mutex            sync.RWMutex           // 读写锁保护并发访问
+	messageChan      chan *ClientMessage    // 接收普通消息的通道
+	broadcastChan    chan *ClientMessage    // 广播消息通道
+	registerChan     chan net.Conn          // 注册新客户端连接的通道
+	unregisterChan   chan net.Conn          // 取消注册客户端连接的通道
+	Done             chan struct{}          // 控制服务停止的信号通道
 	userDB           *db.UserDB
 	asyncQueue       *rdb.RedisQueueClient
+	users            auth.UserStore               // 账号存储，后端由 CHAT_AUTH_BACKEND 选择
+	middlewares      []MessageMiddleware          // 已启用的消息中间件链
+	bots             []Bot                        // 已启用的进程内机器人
+	commands         *CommandRegistry             // 斜杠命令注册表
+	admins           map[string]bool              // 管理员用户名集合
+	historyMaxPage   int64                        // /history 单页记录数上限
+	janitorInterval  time.Duration                // 历史清理协程的执行间隔
+	stats            *stats.Collector             // 运行统计收集器
+	httpAddr         string                       // HTTP 监听地址，为空表示不启用
+	httpServer       *http.Server                 // HTTP 服务（/metrics 等）
+	adminToken       string                       // HTTP 管理 API 的访问令牌
+	listening        atomic.Bool                  // TCP 监听是否正在接受连接
+	sessionSigner    *session.Signer              // 会话令牌签发器
+	sessionTTL       time.Duration                // 会话令牌有效期
+	sessions         map[net.Conn]*session.Claims // 已登录连接当前使用的会话令牌
+	passwordPolicy   PasswordPolicy               // 注册和修改密码时使用的密码强度策略
+	duplicateLogin   DuplicateLoginPolicy         // 同一账号重复登录时的处理策略
 }
 
 // NewServer 创建一个新的服务器实例并初始化相关字段
-// 返回一个指向 Server 的指针
-func NewServer() *Server {
-
-	redisAddr := "localhost:6379"
-	redisPassword := ""
-	redisDB := 2
-	rdb.NewRedisQueueClient(redisAddr, redisPassword, redisDB)
+// 参数 cfg 是服务器运行配置
+// 返回一个指向 Server 的指针；账号存储无法按配置创建时返回错误，服务器不应启动
+func NewServer(cfg *config.Config) (*Server, error) {
 	s := &Server{
-		clients:          make(map[string]net.Conn),
+		clients:          make(map[string][]net.Conn),
 		clientConnToName: make(map[net.Conn]string),
+		connectedAt:      make(map[net.Conn]time.Time),
 		messageChan:      make(chan *ClientMessage, 100),
 		broadcastChan:    make(chan *ClientMessage, 100),
 		registerChan:     make(chan net.Conn, 10),
 		unregisterChan:   make(chan net.Conn, 10),
 		Done:             make(chan struct{}),
+		middlewares:      buildMiddlewareChain(cfg),
+		bots:             buildBots(cfg),
+		commands:         newCommandRegistry(),
+		admins:           make(map[string]bool),
+		historyMaxPage:   int64(cfg.HistoryMaxPage),
+		stats:            stats.NewCollector(),
+		httpAddr:         cfg.HTTPAddr,
+		adminToken:       cfg.AdminToken,
+		sessionTTL:       cfg.SessionTTL,
+		sessions:         make(map[net.Conn]*session.Claims),
+		passwordPolicy: PasswordPolicy{
+			MinLength:      cfg.PasswordMinLength,
+			MinClasses:     cfg.PasswordMinClasses,
+			RejectUsername: cfg.PasswordRejectUsername,
+		},
 	}
-	s.userDB = db.ConnectDB()
-	s.asyncQueue = rdb.NewRedisQueueClient(redisAddr, redisPassword, redisDB)
-	if s.asyncQueue != nil && s.asyncQueue.Client != nil {
-		ctx := context.Background()
-
-		// 删除 Stream Key
-		if err := s.asyncQueue.Client.Del(ctx, rdb.ChatStreamKey).Err(); err != nil {
-			fmt.Printf("警告：启动时清空 Redis Stream 失败: %v\n", err)
+	s.duplicateLogin = parseDuplicateLoginPolicy(cfg.DuplicateLogin)
+	for _, name := range cfg.Admins {
+		s.admins[name] = true
+	}
+	s.registerBuiltinCommands()
+	signer, err := session.NewSigner(cfg.SessionSecret)
+	if err != nil {
+		slog.Warn("会话令牌功能不可用", "error", err)
+	} else {
+		s.sessionSigner = signer
+		if cfg.SessionSecret == "" {
+			slog.Warn("未设置 CHAT_SESSION_SECRET，使用随机密钥，重启后已签发的会话令牌将失效")
 		}
-
-		// 删除 Rank Key
-		if err := s.asyncQueue.Client.Del(ctx, rdb.ChatRankKey).Err(); err != nil {
-			fmt.Printf("警告：启动时清空 Redis Rank Set 失败: %v\n", err)
+	}
+	s.userDB = db.ConnectDB(cfg.MySQLDSN())
+	if s.userDB != nil {
+		s.userDB.QueryObserver = s.stats.ObserveDBQuery
+		s.userDB.BcryptCost = cfg.BcryptCost
+		if _, err := s.userDB.MigrateUp(); err != nil {
+			slog.Error("数据库迁移失败", "error", err)
+		} else if n, err := s.userDB.BackfillNameSkeletons(); err != nil {
+			slog.Error("补全昵称骨架失败", "error", err)
+		} else if n > 0 {
+			slog.Info("已补全昵称骨架", "count", n)
+		}
+	}
+	users, err := buildUserStore(cfg, s.userDB)
+	if err != nil {
+		s.userDB.Close()
+		return nil, err
+	}
+	s.users = users
+	s.asyncQueue = rdb.NewRedisQueueClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	if s.asyncQueue != nil {
+		s.asyncQueue.Retention = rdb.RetentionPolicy{
+			MaxEntries:    int64(cfg.HistoryMaxEntries),
+			MaxAge:        cfg.HistoryMaxAge,
+			KeepOnRestart: cfg.HistoryKeepOnRestart,
+		}
+		s.asyncQueue.LoginGuard = rdb.LoginGuardPolicy{
+			MaxUserFailures: cfg.LoginMaxUserFailures,
+			MaxIPFailures:   cfg.LoginMaxIPFailures,
+			FailureWindow:   cfg.LoginFailureWindow,
+			LockoutDuration: cfg.LoginLockout,
+			DelayStep:       cfg.LoginDelayStep,
+			MaxDelay:        cfg.LoginMaxDelay,
+		}.Sanitize()
+		if !s.asyncQueue.Retention.KeepOnRestart {
+			// 不保留历史：启动时清空聊天历史 Stream 和活跃度排名
+			if err := s.asyncQueue.ResetChatData(); err != nil {
+				slog.Warn("启动时清空聊天历史失败", "error", err)
+			}
 		}
 	}
+	s.janitorInterval = cfg.HistoryJanitorInterval
 
-	return s
+	return s, nil
 }
 
 // ValidateName 验证用户名是否合法
-// 参数 name 表示待验证的用户名字符串
+// 参数 name 表示待验证的用户名字符串，应已经过 nickname.Normalize 归一化
 // 返回值 valid 表示验证结果，reason 提供错误原因描述
 func ValidateName(name string) (bool, string) {
-	if len(name) == 0 {
-		return false, "昵称不能为空"
-	}
-
-	if len(name) > 20 {
-		return false, "昵称长度不能超过20个字符"
-	}
-
-	for _, char := range name {
-		if char < 32 || char > 126 {
-			return false, "昵称包含非法字符"
-		}
-		if strings.ContainsRune("\\/:*?\"<>|", char) {
-			return false, "昵称包含不允许的特殊字符"
-		}
-	}
-
-	return true, ""
+	return nickname.Validate(name)
 }
@@ -1,11 +1,16 @@
 package internal
 
 import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/nickname"
 	"GoWork_4/tools"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"slices"
 	"strings"
+	"time"
 )
 
 // handleLogin 处理客户端登录过程
@@ -24,7 +29,7 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 			return true // 客户端断开
 		}
 
-		nameInput = strings.TrimSpace(nameInput)
+		nameInput = nickname.Normalize(nameInput)
 
 		// 1. 昵称格式验证
 		if valid, reason := ValidateName(nameInput); !valid {
@@ -35,8 +40,17 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 			continue
 		}
 
-		// 2. 在线状态检查
-		if s.isNameTaken(nameInput) {
+		// 2. 在线状态检查：机器人占用的昵称不能登录，账号已在线时按重复登录策略处理
+		if s.isBotName(nameInput) {
+			s.recordAuthEvent(conn, db.AuthEventLogin, nameInput, db.AuthOutcomeFailure, "昵称被机器人占用")
+			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 已被机器人占用，请重新输入昵称：", nameInput))
+			if err != nil {
+				return false
+			}
+			continue
+		}
+		if s.rejectsDuplicateLogin(nameInput) {
+			s.recordAuthEvent(conn, db.AuthEventLogin, nameInput, db.AuthOutcomeFailure, "昵称已在线")
 			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 已在线，请重新输入昵称：", nameInput))
 			if err != nil {
 				return false
@@ -45,9 +59,9 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 		}
 
 		// 3. 数据库注册状态检查
-		isRegistered, err := s.userDB.CheckNameExists(nameInput)
+		isRegistered, err := s.users.Exists(nameInput)
 		if err != nil {
-			fmt.Printf("[DB 错误] 检查用户名 '%s' 失败: %v\n", nameInput, err)
+			slog.Error("检查用户名失败", "user", nameInput, "remote_addr", conn.RemoteAddr().String(), "error", err)
 			err := tools.SendMessage(conn, "服务器数据库错误，请稍后再试。")
 			if err != nil {
 				return false
@@ -57,6 +71,7 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 
 		if !isRegistered {
 			// 昵称未注册，要求用户返回主菜单选择注册
+			s.recordAuthEvent(conn, db.AuthEventLogin, nameInput, db.AuthOutcomeFailure, "用户不存在")
 			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 未注册，请返回主菜单选择注册。", nameInput))
 			if err != nil {
 				return false
@@ -68,15 +83,19 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 		break // 昵称校验通过，进入密码输入
 	}
 
+	if s.rejectIfLocked(conn, name) {
+		return false // 账号或 IP 处于锁定期，回到主菜单
+	}
+
 	err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 已注册，请输入密码：", name))
 	if err != nil {
 		return false
 	}
 
 	for {
-		password, err := tools.ReceiveMessage(conn)
+		password, err := receivePassword(conn)
 		if err != nil {
-			fmt.Printf("有问题")
+			slog.Debug("等待密码时客户端断开", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
 			return true
 		}
 		if password == "" {
@@ -86,26 +105,36 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 			}
 			continue
 		}
-		success, err := s.userDB.CheckCredentials(name, password)
+		if s.rejectIfLocked(conn, name) {
+			return false // 其他连接的失败尝试可能已触发锁定
+		}
+		success, err := s.users.Verify(name, password)
 		if success && err == nil {
+			// 账号已在线时，takeover 策略下先询问是否顶替原会话
+			if proceed, disconnected := s.confirmTakeover(conn, name); !proceed {
+				return disconnected
+			}
 			// 登录成功
-			s.registerClient(conn, name)
+			s.stats.RecordLogin(true)
+			s.recordAuthEvent(conn, db.AuthEventLogin, name, db.AuthOutcomeSuccess, "")
+			s.clearLoginFailures(name)
+			first := s.registerClient(conn, name)
+			s.takeOverSessions(conn, name)
 			err := tools.SendMessage(conn, fmt.Sprintf("欢迎 %s！您已成功登录，开始聊天吧...\n使用 /help 查看可用命令", name))
 			if err != nil {
 				return false
 			}
-			s.broadcastChan <- &ClientMessage{
-				Conn:    conn,
-				Name:    name,
-				Message: fmt.Sprintf("系统: %s 加入了聊天室", name),
-				Type:    "system", // 标记为系统消息
+			s.issueSession(conn, name)
+			if first {
+				s.announceJoin(conn, name)
 			}
 			s.handleClientChat(conn, name)
 			return true // 登录成功，退出注册函数
 		}
 		failReason := "登录失败，密码不正确"
 		if err != nil {
-			fmt.Printf("[DB 错误] 登录验证失败: %v\n", err)
+			slog.Error("登录验证失败", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
+			s.recordAuthEvent(conn, db.AuthEventLogin, name, db.AuthOutcomeFailure, "数据库验证错误")
 			failReason = "登录失败：数据库验证错误，即将断开连接。"
 			err := tools.SendMessage(conn, failReason)
 			if err != nil {
@@ -113,6 +142,12 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 			}
 			return true // 数据库错误，断开连接
 		}
+		s.stats.RecordLogin(false)
+		s.recordAuthEvent(conn, db.AuthEventLogin, name, db.AuthOutcomeFailure, "密码错误")
+		slog.Warn("登录失败，密码不正确", "user", name, "remote_addr", conn.RemoteAddr().String(), "attempt", i+1)
+		if s.recordLoginFailure(conn, name) {
+			return false // 本次失败触发了锁定，回到主菜单
+		}
 		err = tools.SendMessage(conn, failReason)
 		if err != nil {
 			return false
@@ -129,7 +164,9 @@ func (s *Server) handleLogin(conn net.Conn) bool {
 		}
 	}
 	// 3 次密码输入失败
-	err = tools.SendMessage(conn, "密码输入错误次数过多，请重新输入昵称。")
+	slog.Warn("密码输入错误次数过多", "user", name, "remote_addr", conn.RemoteAddr().String())
+	s.recordAuthEvent(conn, db.AuthEventLockout, name, db.AuthOutcomeFailure, "密码连续错误 3 次")
+	err = tools.SendMessage(conn, "密码输入错误次数过多，请返回主菜单。")
 	if err != nil {
 		return false
 	}
@@ -152,7 +189,7 @@ func (s *Server) handleRegistrationLogic(conn net.Conn) bool {
 			return true // 客户端断开
 		}
 
-		nameInput = strings.TrimSpace(nameInput)
+		nameInput = nickname.Normalize(nameInput)
 
 		// 1. 昵称格式验证 (假设存在 ValidateName 函数)
 		if valid, reason := ValidateName(nameInput); !valid {
@@ -173,9 +210,9 @@ func (s *Server) handleRegistrationLogic(conn net.Conn) bool {
 		}
 
 		// 3. 数据库注册状态检查
-		isRegistered, err := s.userDB.CheckNameExists(nameInput)
+		isRegistered, err := s.users.Exists(nameInput)
 		if err != nil {
-			fmt.Printf("[DB 错误] 检查用户名 '%s' 失败: %v\n", nameInput, err)
+			slog.Error("检查用户名失败", "user", nameInput, "remote_addr", conn.RemoteAddr().String(), "error", err)
 			err := tools.SendMessage(conn, "服务器数据库错误，请稍后再试。")
 			if err != nil {
 				return false
@@ -185,45 +222,119 @@ func (s *Server) handleRegistrationLogic(conn net.Conn) bool {
 
 		if isRegistered {
 			// 昵称已注册，引导用户返回主菜单选择登录
+			s.recordAuthEvent(conn, db.AuthEventRegister, nameInput, db.AuthOutcomeFailure, "昵称已注册")
 			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 已注册，请返回主菜单选择登录。", nameInput))
 			if err != nil {
-				log.Printf("注册失败了handleBroadcasts")
+				slog.Warn("发送注册提示失败", "user", nameInput, "remote_addr", conn.RemoteAddr().String(), "error", err)
 				return false
 			}
 			return false // 返回 false，回到主循环选择菜单
 		}
 
+		// 4. 易混淆检查：拒绝仅大小写或形近字符不同的昵称，防止冒充
+		if similar, ok := s.findConfusableOnline(nameInput); ok {
+			s.recordAuthEvent(conn, db.AuthEventRegister, nameInput, db.AuthOutcomeFailure, "昵称与在线用户相似")
+			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 与在线用户 '%s' 过于相似，请重新输入昵称：", nameInput, similar))
+			if err != nil {
+				return false
+			}
+			continue
+		}
+		similar, found, err := s.users.FindConfusable(nameInput)
+		if err != nil {
+			slog.Error("检查相似用户名失败", "user", nameInput, "remote_addr", conn.RemoteAddr().String(), "error", err)
+			err := tools.SendMessage(conn, "服务器数据库错误，请稍后再试。")
+			if err != nil {
+				return false
+			}
+			return true // 数据库错误，断开连接
+		}
+		if found {
+			s.recordAuthEvent(conn, db.AuthEventRegister, nameInput, db.AuthOutcomeFailure, "昵称与已注册用户相似")
+			err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 与已注册用户 '%s' 过于相似，请重新输入昵称：", nameInput, similar))
+			if err != nil {
+				return false
+			}
+			continue
+		}
+
 		name = nameInput
 		break // 昵称校验通过，进入密码输入
 	}
 
-	err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 可用。请输入密码进行注册：", name))
+	err := tools.SendMessage(conn, fmt.Sprintf("昵称 '%s' 可用。请输入密码进行注册（%s）：", name, s.passwordPolicy.Describe()))
 	if err != nil {
 		return false
 	}
 
-	password, err := tools.ReceiveMessage(conn)
-	if err != nil {
-		return true // 客户端断开，退出
+	var password string
+	for attempt := 1; ; attempt++ {
+		input, err := receivePassword(conn)
+		if err != nil {
+			return true // 客户端断开，退出
+		}
+		reason, disconnected := s.confirmNewPassword(conn, name, input, "请再次输入密码：")
+		if disconnected {
+			return true
+		}
+		if reason == "" {
+			password = input
+			break
+		}
+		s.recordAuthEvent(conn, db.AuthEventRegister, name, db.AuthOutcomeFailure, reason)
+		if attempt >= maxRegisterPasswordAttempts {
+			tools.SendMessage(conn, fmt.Sprintf("注册失败：%s。尝试次数过多，请返回主菜单。", reason))
+			return false // 返回 false，回到主菜单
+		}
+		if err := tools.SendMessage(conn, "注册失败："+reason); err != nil {
+			return false
+		}
+		if err := tools.SendMessage(conn, "请重新输入密码："); err != nil {
+			return false
+		}
 	}
 
-	if password == "" {
-		tools.SendMessage(conn, "密码不能为空，请重新输入昵称：")
-		return false // 返回 false，回到外层循环重新输入昵称
+	err = s.users.Register(name, password)
+	if errors.Is(err, db.ErrNameConflict) {
+		// 等待输入密码期间，其他连接可能已注册了相同或相似的昵称
+		s.recordAuthEvent(conn, db.AuthEventRegister, name, db.AuthOutcomeFailure, "昵称已被注册或与已注册用户相似")
+		tools.SendMessage(conn, fmt.Sprintf("注册失败：昵称 '%s' 已被注册或与已注册用户过于相似，请返回主菜单。", name))
+		return false // 返回 false，回到主菜单
 	}
-
-	err = s.userDB.RegisterUser(name, password)
 	if err != nil {
 		// 注册失败
-		fmt.Printf("[DB 错误] 注册失败: %v\n", err)
-		tools.SendMessage(conn, "注册失败：数据库写入错误。请重新输入昵称：")
-		return false // 返回 false，回到外层循环重新输入昵称
+		slog.Error("注册失败", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
+		s.recordAuthEvent(conn, db.AuthEventRegister, name, db.AuthOutcomeFailure, "数据库写入错误")
+		tools.SendMessage(conn, "注册失败：数据库写入错误，请返回主菜单。")
+		return false // 返回 false，回到主菜单
 	}
+	s.recordAuthEvent(conn, db.AuthEventRegister, name, db.AuthOutcomeSuccess, "")
 	tools.SendMessage(conn, fmt.Sprintf("恭喜 %s 注册成功！请返回主菜单。", name))
 
 	return false // 注册成功，退出注册函数
 }
 
+// confirmNewPassword 按密码策略校验新密码，通过后要求用户再次输入确认
+// 参数 password 应经由 receivePassword 或 CommandContext.PromptPassword 读取，prompt 是再次输入时的提示，
+// 聊天中调用时应经由 tools.FormatPasswordPrompt 加上密码提示标记
+// 返回值 reason 为空表示校验通过，否则为失败原因；disconnected 表示等待确认时客户端已断开
+func (s *Server) confirmNewPassword(conn net.Conn, name, password, prompt string) (reason string, disconnected bool) {
+	if valid, why := s.passwordPolicy.Validate(name, password); !valid {
+		return why, false
+	}
+	if err := tools.SendMessage(conn, prompt); err != nil {
+		return "", true
+	}
+	confirm, err := receivePassword(conn)
+	if err != nil {
+		return "", true
+	}
+	if confirm != password {
+		return "两次输入的密码不一致", false
+	}
+	return "", false
+}
+
 // handleClientChat 负责接收并转发客户端发送的消息，支持命令解析和私聊功能
 // 参数 conn 是客户端的网络连接，name 是该用户的昵称
 func (s *Server) handleClientChat(conn net.Conn, name string) {
@@ -246,7 +357,7 @@ func (s *Server) handleClientChat(conn net.Conn, name string) {
 func (s *Server) handleClientChatAndCommand(conn net.Conn, name, input string) {
 	input = strings.TrimSpace(input)
 	if strings.HasPrefix(input, "/") {
-		if s.handleCommand(conn, input) {
+		if s.handleCommand(conn, name, input) {
 			return
 		}
 	}
@@ -279,7 +390,7 @@ func (s *Server) handlePrivateMessage(conn net.Conn, sender string, message stri
 
 	// 如果 parts[0] 是 @targetName，需要移除 @ 符号
 	targetNameWithAt := strings.TrimSpace(parts[0])
-	targetName := strings.TrimPrefix(targetNameWithAt, "@")
+	targetName := nickname.Normalize(strings.TrimPrefix(targetNameWithAt, "@"))
 
 	if len(parts) < 2 || targetName == "" {
 		tools.SendMessage(conn, "【系统】私聊格式错误，请使用: @用户名 消息内容")
@@ -294,7 +405,7 @@ func (s *Server) handlePrivateMessage(conn net.Conn, sender string, message stri
 	}
 
 	// 2. 查找目标用户（在新架构中，我们只检查**目标是否在线**，实际发送交给 broadcastMessage）
-	if _, exists := s.getClientConnection(targetName); !exists {
+	if !s.isNameTaken(targetName) {
 		tools.SendMessage(conn, fmt.Sprintf("【系统】用户 '%s' 不在线或不存在", targetName))
 		return
 	}
@@ -315,102 +426,93 @@ func (s *Server) handlePrivateMessage(conn net.Conn, sender string, message stri
 }
 
 // handleCommand 解析并执行客户端发送的命令
-// 参数 conn 是客户端连接，message 是命令文本
+// 参数 conn 是客户端连接，name 是发送者昵称，message 是命令文本
 // 返回布尔值表示是否是有效命令
-func (s *Server) handleCommand(conn net.Conn, message string) bool {
+func (s *Server) handleCommand(conn net.Conn, name, message string) bool {
 	if len(message) > 0 && message[0] == '/' {
 		parts := strings.Fields(message)
 		if len(parts) == 0 {
 			return true
 		}
 
-		command := parts[0]
-		switch command {
-		case "/list":
-			tools.SendMessage(conn, s.getOnlineUsers())
-		case "/help":
-			helpMsg := `可用命令：
-/list - 查看在线用户
-/help - 显示帮助信息
-/history或/h - 查看最近的10条历史消息
-/rank - 查看活跃度排名前五的用户
-/exit - 退出
-私聊功能：
-@用户名 消息内容 - 发送私聊消息
-例如: @张三 你好！`
-			tools.SendMessage(conn, helpMsg)
-		case "/history", "/h":
-			const defaultHistoryCount = 10
-			if s.asyncQueue == nil || s.asyncQueue.Client == nil {
-				tools.SendMessage(conn, "系统：历史记录功能当前不可用(Redis未连接)")
-				break
-			}
-			history, err := s.asyncQueue.GetChatHistory(defaultHistoryCount)
-			if err != nil {
-				tools.SendMessage(conn, fmt.Sprintf("系统：获取历史记录"))
-			}
-			if len(history) == 0 {
-				tools.SendMessage(conn, "系统,暂无聊天历史记录")
-			} else {
-				msg := fmt.Sprintf("--- 最近 %d 条聊天历史记录 ---\n%s\n--- 历史记录结束 ---", len(history), strings.Join(history, "\n"))
-				tools.SendMessage(conn, msg)
-			}
-		case "/rank":
-			const defaultRankCount = 5
-			if s.asyncQueue == nil || s.asyncQueue.Client == nil {
-				tools.SendMessage(conn, "系统：活跃度排名功能当前不可用（Redis未连接）")
-			}
-			rankList, err := s.asyncQueue.GetActivityRank(defaultRankCount)
-			if err != nil {
-				tools.SendMessage(conn, fmt.Sprintf("系统：获取活跃度排名失败：%v", err))
-				break
-			}
-			if len(rankList) == 0 {
-				tools.SendMessage(conn, "系统：暂无活跃度数据。")
-			} else {
-				msg := fmt.Sprintf("--- 活跃度排名前 %d 用户 ---\n%s\n--- 排名结束 ---", len(rankList), strings.Join(rankList, "\n"))
-				tools.SendMessage(conn, msg)
-			}
+		role := s.roleOf(name)
+		cmd, ok := s.commands.Lookup(parts[0])
+		if !ok || cmd.Role > role {
+			tools.SendMessage(conn, s.unknownCommandMessage(parts[0], role))
+			return true
+		}
 
-		default:
-			tools.SendMessage(conn, "未知命令，使用 /help 查看可用命令")
+		args := parts[1:]
+		if !cmd.acceptsArgs(len(args)) {
+			tools.SendMessage(conn, fmt.Sprintf("参数错误，用法：%s", cmd.usageLine()))
+			return true
 		}
+
+		cmd.Handler(&CommandContext{Conn: conn, Name: name, Args: args})
 		return true
 	}
 	return false
 }
 
-// getClientConnection 获取指定用户名对应的客户端连接
+// findConfusableOnline 查找与 name 骨架相同的在线用户（包括机器人）
+// 返回值 similar 是相似的在线用户名，ok 表示是否存在
+func (s *Server) findConfusableOnline(name string) (similar string, ok bool) {
+	skeleton := nickname.Skeleton(name)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for online := range s.clients {
+		if nickname.Skeleton(online) == skeleton {
+			return online, true
+		}
+	}
+	return "", false
+}
+
+// getClientConnections 获取指定用户名的所有已登录连接
 // 参数 name 是目标用户名
-// 返回值 conn 是对应连接，exists 标识是否存在
-func (s *Server) getClientConnection(name string) (net.Conn, bool) {
+// 返回值按登录顺序排列，用户不在线时为空
+func (s *Server) getClientConnections(name string) []net.Conn {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	conn, exists := s.clients[name]
-	return conn, exists
+	return slices.Clone(s.clients[name])
 }
 
-// isNameTaken 判断某个用户名是否已被占用
+// isNameTaken 判断某个用户名是否在线（至少有一个已登录的连接）
 // 参数 name 是待检测的用户名
-// 返回布尔值表示是否已被占用
+// 返回布尔值表示是否在线
 func (s *Server) isNameTaken(name string) bool {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	_, exists := s.clients[name]
-	return exists
+	return len(s.clients[name]) > 0
+}
+
+// isBotName 判断某个用户名是否被在线的机器人占用
+// 用户可以在多个连接上同时登录，但不能与机器人共用昵称
+func (s *Server) isBotName(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	conns := s.clients[name]
+	return len(conns) > 0 && isBotConn(conns[0])
 }
 
 // registerClient 将新客户端注册进服务器内部数据结构中
 // 参数 conn 是客户端连接，name 是其昵称
-func (s *Server) registerClient(conn net.Conn, name string) {
+// 返回值表示这是否是该用户的第一个会话，只有第一个会话上线时才广播上线消息
+func (s *Server) registerClient(conn net.Conn, name string) bool {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.clients[name] = conn
+	first := len(s.clients[name]) == 0
+	s.clients[name] = append(s.clients[name], conn)
 	s.clientConnToName[conn] = name
+	s.connectedAt[conn] = time.Now()
+	s.stats.SetOnline(len(s.clients))
 
-	fmt.Printf("客户端注册成功: %s (%s)\n", name, conn.RemoteAddr())
+	slog.Info("客户端注册成功", "user", name, "remote_addr", conn.RemoteAddr().String(), "online", len(s.clients), "sessions", len(s.clients[name]))
+	if !first {
+		return false
+	}
 
 	// 发送用户上线系统消息
 	joinMsg := fmt.Sprintf("【系统消息】用户 %s 上线了！当前在线人数: %d", name, len(s.clients))
@@ -421,37 +523,75 @@ func (s *Server) registerClient(conn net.Conn, name string) {
 		Conn:    nil,
 	}
 	s.messageChan <- systemMsg
+	return true
+}
 
-	fmt.Printf("当前在线用户: %d\n", len(s.clients))
+// announceJoin 广播用户加入聊天室的系统消息
+// 与上下线消息一样经由 messageChan 发送，从而经过消息中间件链
+func (s *Server) announceJoin(conn net.Conn, name string) {
+	s.messageChan <- &ClientMessage{
+		Conn:    conn,
+		Name:    name,
+		Message: fmt.Sprintf("系统: %s 加入了聊天室", name),
+		Type:    "system", // 标记为系统消息
+	}
 }
 
 // removeClient 从服务器移除指定客户端连接及其相关信息
 // 参数 conn 是需要移除的客户端连接
+// 用户的最后一个会话断开时才广播下线消息
 func (s *Server) removeClient(conn net.Conn) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if name, exists := s.clientConnToName[conn]; exists {
-		if currentConn, userExists := s.clients[name]; userExists && currentConn == conn {
-			// ... existing logic to delete from maps
-			delete(s.clients, name)          // s.clients 长度变为 0
-			delete(s.clientConnToName, conn) // s.clientConnToName 长度变为 0
-			currentOnline := len(s.clients)  // currentOnline = 0 (准确)
-			// **新增/修改：发送用户下线系统消息**
-			leaveMsg := fmt.Sprintf("【系统消息】用户 %s 离开了！当前在线人数: %d", name, len(s.clients)-1)
-			systemMsg := &ClientMessage{
-				Name:    "[系统]",
-				Message: leaveMsg,
-				Type:    "system", // 标记为系统消息
-				Conn:    nil,
-			}
-			s.messageChan <- systemMsg
+	name, exists := s.clientConnToName[conn]
+	if !exists {
+		return
+	}
+	conns := s.clients[name]
+	i := slices.Index(conns, conn)
+	if i < 0 {
+		return
+	}
+	conns = slices.Delete(conns, i, i+1)
+	delete(s.clientConnToName, conn)
+	delete(s.connectedAt, conn) // 清除上线时间
+	delete(s.sessions, conn)    // 令牌保持有效，客户端可凭令牌恢复会话
+	conn.Close()
+
+	if len(conns) > 0 {
+		s.clients[name] = conns
+		slog.Info("客户端会话断开", "user", name, "remote_addr", conn.RemoteAddr().String(), "sessions", len(conns))
+		return
+	}
+
+	delete(s.clients, name)
+	currentOnline := len(s.clients)
+	s.stats.SetOnline(currentOnline)
+	leaveMsg := fmt.Sprintf("【系统消息】用户 %s 离开了！当前在线人数: %d", name, currentOnline)
+	systemMsg := &ClientMessage{
+		Name:    "[系统]",
+		Message: leaveMsg,
+		Type:    "system", // 标记为系统消息
+		Conn:    nil,
+	}
+	s.messageChan <- systemMsg
+
+	slog.Info("客户端移除成功", "user", name, "remote_addr", conn.RemoteAddr().String(), "online", currentOnline)
+}
 
-			fmt.Printf("客户端移除成功: %s (%s)\n", name, conn.RemoteAddr())
-			fmt.Printf("当前在线用户: %d\n", currentOnline)
-			conn.Close()
+// disconnectUser 向指定用户的所有会话发送通知后断开连接
+// 参数 name 是目标用户名，notice 是断开前发送的通知（为空则不发送）
+// 返回值表示该用户是否在线
+func (s *Server) disconnectUser(name, notice string) bool {
+	conns := s.getClientConnections(name)
+	for _, conn := range conns {
+		if notice != "" {
+			tools.SendMessage(conn, notice)
 		}
+		s.unregisterChan <- conn
 	}
+	return len(conns) > 0
 }
 
 // getOnlineUsers 获取当前在线的所有用户名列表
@@ -465,7 +605,12 @@ func (s *Server) getOnlineUsers() string {
 	}
 
 	var users []string
-	for name := range s.clients {
+	for name, conns := range s.clients {
+		if isBotConn(conns[0]) {
+			name += "[机器人]"
+		} else if len(conns) > 1 {
+			name += fmt.Sprintf("[%d个会话]", len(conns))
+		}
 		users = append(users, name)
 	}
 
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"GoWork_4/tools"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Role 用户角色，决定可执行的命令范围
+type Role int
+
+const (
+	RoleUser  Role = iota // 普通用户
+	RoleAdmin             // 管理员
+)
+
+// String 返回角色的中文名称
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "管理员"
+	default:
+		return "普通用户"
+	}
+}
+
+// CommandContext 命令执行上下文
+type CommandContext struct {
+	Conn net.Conn // 发送命令的客户端连接
+	Name string   // 发送命令的用户名
+	Args []string // 命令参数（不含命令名本身）
+}
+
+// Reply 向命令发送者回复消息
+func (ctx *CommandContext) Reply(msg string) {
+	tools.SendMessage(ctx.Conn, msg)
+}
+
+// Prompt 向命令发送者发送提示并等待其下一条输入
+// 命令处理函数运行在该连接的读取协程中，因此可以直接读取连接而不会与聊天消息交错
+// 提示带有 tools.InputPromptPrefix 标记，客户端据此把下一行输入作为回答发送
+func (ctx *CommandContext) Prompt(msg string) (string, error) {
+	if err := tools.SendMessage(ctx.Conn, tools.FormatInputPrompt(msg)); err != nil {
+		return "", err
+	}
+	input, err := tools.ReceiveMessage(ctx.Conn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// PromptPassword 向用户发送提示并等待其输入密码，处理方式与登录、注册时一致
+// 提示带有 tools.PasswordPromptPrefix 标记，客户端据此以不回显的方式读取密码
+func (ctx *CommandContext) PromptPassword(msg string) (string, error) {
+	if err := tools.SendMessage(ctx.Conn, tools.FormatPasswordPrompt(msg)); err != nil {
+		return "", err
+	}
+	return receivePassword(ctx.Conn)
+}
+
+// Command 斜杠命令定义
+// /help 的输出和参数校验均由这里声明的信息生成，新增命令只需注册即可。
+type Command struct {
+	Name    string                    // 命令名（不含前导 /）
+	Aliases []string                  // 命令别名（不含前导 /）
+	Usage   string                    // 参数说明，如 "[n]"、"<用户名>"
+	MinArgs int                       // 最少参数个数
+	MaxArgs int                       // 最多参数个数，-1 表示不限
+	Help    string                    // 一行帮助说明
+	Role    Role                      // 执行命令所需的最低角色
+	Handler func(ctx *CommandContext) // 命令处理函数
+}
+
+// usageLine 返回命令的完整用法，如 "/history [n]"
+func (c *Command) usageLine() string {
+	if c.Usage == "" {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + c.Usage
+}
+
+// acceptsArgs 判断参数个数是否在命令允许的范围内
+func (c *Command) acceptsArgs(n int) bool {
+	return n >= c.MinArgs && (c.MaxArgs < 0 || n <= c.MaxArgs)
+}
+
+// CommandRegistry 命令注册表，按名称和别名索引命令
+type CommandRegistry struct {
+	commands []*Command          // 按注册顺序保存的命令，用于生成帮助
+	index    map[string]*Command // 名称和别名到命令的映射
+}
+
+// newCommandRegistry 创建一个空的命令注册表
+func newCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{index: make(map[string]*Command)}
+}
+
+// Register 注册命令，名称或别名重复时后注册的覆盖先注册的
+func (r *CommandRegistry) Register(cmd *Command) {
+	r.commands = append(r.commands, cmd)
+	r.index[cmd.Name] = cmd
+	for _, alias := range cmd.Aliases {
+		r.index[alias] = cmd
+	}
+}
+
+// Lookup 根据名称或别名查找命令，name 可以带或不带前导 /
+func (r *CommandRegistry) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.index[strings.TrimPrefix(name, "/")]
+	return cmd, ok
+}
+
+// Suggest 为未知命令寻找最相近的已注册命令，找不到时返回空字符串
+func (r *CommandRegistry) Suggest(name string, role Role) string {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return ""
+	}
+	best, bestDist := "", 3 // 编辑距离超过 2 不再提示
+	keys := make([]string, 0, len(r.index))
+	for key := range r.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if r.index[key].Role > role {
+			continue
+		}
+		dist := levenshtein(name, key)
+		if strings.HasPrefix(key, name) {
+			dist = 0 // 输入是命令前缀时优先提示
+		}
+		if dist < bestDist {
+			best, bestDist = key, dist
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return "/" + best
+}
+
+// HelpText 生成指定角色可见的命令列表
+func (r *CommandRegistry) HelpText(role Role) string {
+	var sb strings.Builder
+	sb.WriteString("可用命令：")
+	for _, cmd := range r.commands {
+		if cmd.Role > role {
+			continue
+		}
+		sb.WriteString("\n" + cmd.usageLine())
+		if len(cmd.Aliases) > 0 {
+			sb.WriteString("（别名: /" + strings.Join(cmd.Aliases, ", /") + "）")
+		}
+		sb.WriteString(" - " + cmd.Help)
+	}
+	sb.WriteString("\n使用 /help <命令> 查看命令详情")
+	sb.WriteString("\n私聊功能：\n@用户名 消息内容 - 发送私聊消息\n例如: @张三 你好！")
+	return sb.String()
+}
+
+// CommandHelp 生成单个命令的详细帮助
+func (r *CommandRegistry) CommandHelp(cmd *Command) string {
+	lines := []string{
+		fmt.Sprintf("命令: /%s", cmd.Name),
+		fmt.Sprintf("用法: %s", cmd.usageLine()),
+		fmt.Sprintf("说明: %s", cmd.Help),
+	}
+	if len(cmd.Aliases) > 0 {
+		lines = append(lines, "别名: /"+strings.Join(cmd.Aliases, ", /"))
+	}
+	lines = append(lines, fmt.Sprintf("权限: %s", cmd.Role))
+	return strings.Join(lines, "\n")
+}
+
+// levenshtein 计算两个字符串按字符计的编辑距离
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,83 @@
+package internal
+
+import "testing"
+
+func TestCommandAcceptsArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		minArgs int
+		maxArgs int
+		n       int
+		want    bool
+	}{
+		{"无参数命令不带参数", 0, 0, 0, true},
+		{"无参数命令带参数", 0, 0, 1, false},
+		{"可选参数省略", 0, 1, 0, true},
+		{"可选参数提供", 0, 1, 1, true},
+		{"参数过多", 0, 1, 2, false},
+		{"缺少必需参数", 1, 2, 0, false},
+		{"不限参数个数", 1, -1, 5, true},
+		{"不限个数但缺少必需参数", 1, -1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &Command{Name: "test", MinArgs: tt.minArgs, MaxArgs: tt.maxArgs}
+			if got := cmd.acceptsArgs(tt.n); got != tt.want {
+				t.Fatalf("MinArgs=%d MaxArgs=%d 时 acceptsArgs(%d) = %v，期望 %v", tt.minArgs, tt.maxArgs, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"help", "help", 0},
+		{"", "help", 4},
+		{"hlep", "help", 2},
+		{"hepl", "help", 2},
+		{"histroy", "history", 2},
+		{"kick", "kicks", 1},
+		{"用户", "用戶", 1}, // 按字符而不是字节计算
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d，期望 %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestCommandRegistrySuggest(t *testing.T) {
+	r := newCommandRegistry()
+	r.Register(&Command{Name: "help", Aliases: []string{"h"}})
+	r.Register(&Command{Name: "history"})
+	r.Register(&Command{Name: "kick", Role: RoleAdmin})
+
+	tests := []struct {
+		name  string
+		input string
+		role  Role
+		want  string
+	}{
+		{"拼写错误", "/hlep", RoleUser, "/help"},
+		{"命令前缀", "/hist", RoleUser, "/history"},
+		{"不带前导斜杠", "histroy", RoleUser, "/history"},
+		{"相差太远", "/xyzzy", RoleUser, ""},
+		{"空命令", "/", RoleUser, ""},
+		{"不提示无权执行的命令", "/kik", RoleUser, ""},
+		{"管理员可以得到提示", "/kik", RoleAdmin, "/kick"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Suggest(tt.input, tt.role); got != tt.want {
+				t.Fatalf("Suggest(%q) = %q，期望 %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/nickname"
+	"GoWork_4/chat_server/rdb"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// registerBuiltinCommands 注册服务器内置的斜杠命令
+func (s *Server) registerBuiltinCommands() {
+	s.commands.Register(&Command{
+		Name:    "list",
+		MaxArgs: 0,
+		Help:    "查看在线用户",
+		Handler: s.cmdList,
+	})
+	s.commands.Register(&Command{
+		Name:    "help",
+		Usage:   "[命令]",
+		MaxArgs: 1,
+		Help:    "显示帮助信息",
+		Handler: s.cmdHelp,
+	})
+	s.commands.Register(&Command{
+		Name:    "history",
+		Aliases: []string{"h"},
+		Usage:   "[n] [--before <游标>] [--after <游标>] [--user <用户名>] [--since <时间>]",
+		MaxArgs: -1,
+		Help:    "分页查看聊天历史（默认最近10条，--after 查看游标之后的新消息，--since 支持 30m、2h、2006-01-02、15:04 等格式）",
+		Handler: s.cmdHistory,
+	})
+	s.commands.Register(&Command{
+		Name:    "search",
+		Usage:   "<关键字...>",
+		MinArgs: 1,
+		MaxArgs: -1,
+		Help:    "在已归档的聊天记录中全文搜索",
+		Handler: s.cmdSearch,
+	})
+	s.commands.Register(&Command{
+		Name:    "rank",
+		Usage:   "[day|week|month|all] [n] | me",
+		MaxArgs: 2,
+		Help:    "查看今日/本周/本月/总活跃度排名（默认总榜前5），/rank me 查看自己的名次",
+		Handler: s.cmdRank,
+	})
+	s.commands.Register(&Command{
+		Name:    "profile",
+		Usage:   "[set <name|bio|pronouns|timezone> [内容]]",
+		MaxArgs: -1,
+		Help:    "查看自己的资料，或修改显示名称、简介、代词、时区（内容为空表示清除）",
+		Handler: s.cmdProfile,
+	})
+	s.commands.Register(&Command{
+		Name:    "whois",
+		Usage:   "<用户名>",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Help:    "查看用户的资料、在线状态、连接时长和活跃度",
+		Handler: s.cmdWhois,
+	})
+	s.commands.Register(&Command{
+		Name:    "info",
+		MaxArgs: 0,
+		Help:    "查看服务器状态和聊天历史保留策略",
+		Handler: s.cmdInfo,
+	})
+	s.commands.Register(&Command{
+		Name:    "stats",
+		MaxArgs: 0,
+		Help:    "查看服务器运行统计",
+		Role:    RoleAdmin,
+		Handler: s.cmdStats,
+	})
+	s.commands.Register(&Command{
+		Name:    "audit",
+		Usage:   "[用户名]",
+		MaxArgs: 1,
+		Help:    "查看最近的登录、注册和锁定审计记录",
+		Role:    RoleAdmin,
+		Handler: s.cmdAudit,
+	})
+	s.commands.Register(&Command{
+		Name:    "unlock",
+		Usage:   "<用户名> | ip <地址>",
+		MinArgs: 1,
+		MaxArgs: 2,
+		Help:    "解除账号或 IP 的登录锁定并清空失败计数",
+		Role:    RoleAdmin,
+		Handler: s.cmdUnlock,
+	})
+	s.commands.Register(&Command{
+		Name:    "passwd",
+		MaxArgs: 0,
+		Help:    "修改密码，修改后其他设备上的会话令牌全部失效",
+		Handler: s.cmdPasswd,
+	})
+	s.commands.Register(&Command{
+		Name:    "deleteaccount",
+		MaxArgs: 0,
+		Help:    "注销账号，删除账号和活跃度排名并断开所有会话",
+		Handler: s.cmdDeleteAccount,
+	})
+	s.commands.Register(&Command{
+		Name:    "logout",
+		MaxArgs: 0,
+		Help:    "退出登录并使会话令牌失效",
+		Handler: s.cmdLogout,
+	})
+	s.commands.Register(&Command{
+		Name:    "exit",
+		Aliases: []string{"quit"},
+		MaxArgs: 0,
+		Help:    "退出聊天室",
+		Handler: s.cmdExit,
+	})
+}
+
+// roleOf 返回用户的角色，管理员名单来自配置 CHAT_ADMINS
+func (s *Server) roleOf(name string) Role {
+	if s.admins[name] {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// cmdList 处理 /list 命令
+func (s *Server) cmdList(ctx *CommandContext) {
+	ctx.Reply(s.getOnlineUsers())
+}
+
+// cmdHelp 处理 /help 命令，/help <命令> 显示单个命令的详细说明
+func (s *Server) cmdHelp(ctx *CommandContext) {
+	role := s.roleOf(ctx.Name)
+	if len(ctx.Args) == 0 {
+		ctx.Reply(s.commands.HelpText(role))
+		return
+	}
+	cmd, ok := s.commands.Lookup(ctx.Args[0])
+	if !ok || cmd.Role > role {
+		ctx.Reply(s.unknownCommandMessage(ctx.Args[0], role))
+		return
+	}
+	ctx.Reply(s.commands.CommandHelp(cmd))
+}
+
+// cmdHistory 处理 /history 命令，支持页大小、游标、用户和时间过滤
+func (s *Server) cmdHistory(ctx *CommandContext) {
+	const defaultHistoryCount = 10
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		ctx.Reply("系统：历史记录功能当前不可用(Redis未连接)")
+		return
+	}
+
+	query := rdb.HistoryQuery{Count: defaultHistoryCount}
+	var sinceArg string
+	args := ctx.Args
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--before", "--after", "--user", "--since":
+			if i+1 >= len(args) {
+				ctx.Reply(fmt.Sprintf("参数错误：%s 缺少取值", args[i]))
+				return
+			}
+			value := args[i+1]
+			i++
+			switch args[i-1] {
+			case "--before":
+				query.Before = value
+			case "--after":
+				query.After = value
+			case "--user":
+				query.User = value
+			case "--since":
+				since, err := parseSinceArg(value, time.Now())
+				if err != nil {
+					ctx.Reply(fmt.Sprintf("参数错误：%v", err))
+					return
+				}
+				query.Since = since
+				sinceArg = value
+			}
+		default:
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 {
+				ctx.Reply(fmt.Sprintf("参数错误：无法识别 '%s'，用法：/history [n] [--before <游标>] [--after <游标>] [--user <用户名>] [--since <时间>]", args[i]))
+				return
+			}
+			query.Count = n
+		}
+	}
+
+	maxPage := s.historyMaxPage
+	if maxPage <= 0 {
+		maxPage = defaultHistoryCount
+	}
+	if query.Count > maxPage {
+		query.Count = maxPage
+	}
+
+	page, err := s.asyncQueue.GetChatHistoryPage(query)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：获取历史记录失败：%v", err))
+		return
+	}
+	if len(page.Entries) == 0 {
+		ctx.Reply("系统,暂无聊天历史记录")
+		return
+	}
+
+	lines := make([]string, 0, len(page.Entries))
+	for _, entry := range page.Entries {
+		lines = append(lines, entry.String())
+	}
+	msg := fmt.Sprintf("--- %d 条聊天历史记录 ---\n%s\n--- 历史记录结束 ---", len(lines), strings.Join(lines, "\n"))
+	if page.NextCursor != "" {
+		next := fmt.Sprintf("/history %d --before %s", query.Count, page.NextCursor)
+		if query.After != "" {
+			next = fmt.Sprintf("/history %d --after %s", query.Count, page.NextCursor)
+		}
+		if query.User != "" {
+			next += " --user " + query.User
+		}
+		if sinceArg != "" {
+			next += " --since " + sinceArg
+		}
+		if query.After != "" {
+			msg += fmt.Sprintf("\n下一页游标: %s（查看更新记录: %s）", page.NextCursor, next)
+		} else {
+			msg += fmt.Sprintf("\n下一页游标: %s（查看更早记录: %s）", page.NextCursor, next)
+		}
+	}
+	if query.After != "" {
+		// 附带最后一条记录的 Stream ID，客户端补齐消息后据此更新游标
+		msg = tools.FormatStreamMessage(page.Entries[len(page.Entries)-1].ID, msg)
+	}
+	ctx.Reply(msg)
+}
+
+// parseSinceArg 解析 --since 参数
+// 支持相对时长（如 30m、2h）、日期（2006-01-02）、日期时间（2006-01-02T15:04、RFC3339）以及当天时间（15:04）
+func parseSinceArg(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间 '%s'", value)
+}
+
+// cmdSearch 处理 /search 命令，在 MySQL 归档表中全文搜索聊天记录
+func (s *Server) cmdSearch(ctx *CommandContext) {
+	const maxSearchResults = 20
+	if s.userDB == nil {
+		ctx.Reply("系统：搜索功能当前不可用（数据库未连接）")
+		return
+	}
+	keywords := strings.Join(ctx.Args, " ")
+	results, err := s.userDB.SearchMessages(keywords, maxSearchResults)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：搜索失败：%v", err))
+		return
+	}
+	if len(results) == 0 {
+		ctx.Reply(fmt.Sprintf("系统：没有找到包含 '%s' 的聊天记录", keywords))
+		return
+	}
+
+	lines := make([]string, 0, len(results))
+	for _, msg := range results {
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", msg.SentAt.Format("2006-01-02 15:04:05"), msg.Sender, msg.Content))
+	}
+	ctx.Reply(fmt.Sprintf("--- 搜索 '%s' 共 %d 条结果 ---\n%s\n--- 搜索结束 ---", keywords, len(lines), strings.Join(lines, "\n")))
+}
+
+// cmdRank 处理 /rank 命令，支持按时间窗口查看排行以及查看自己的名次
+func (s *Server) cmdRank(ctx *CommandContext) {
+	const defaultRankCount = 5
+	const maxRankCount = 50
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		ctx.Reply("系统：活跃度排名功能当前不可用（Redis未连接）")
+		return
+	}
+
+	window, count := rdb.RankAll, int64(defaultRankCount)
+	for _, arg := range ctx.Args {
+		if arg == "me" {
+			s.replyOwnRank(ctx)
+			return
+		}
+		if w, ok := rdb.ParseRankWindow(arg); ok {
+			window = w
+			continue
+		}
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n <= 0 {
+			ctx.Reply("参数错误，用法：/rank [day|week|month|all] [n] 或 /rank me")
+			return
+		}
+		count = min(n, maxRankCount)
+	}
+
+	entries, err := s.asyncQueue.GetRankWindow(window, count)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：获取活跃度排名失败：%v", err))
+		return
+	}
+	if len(entries) == 0 {
+		ctx.Reply(fmt.Sprintf("系统：暂无%s活跃度数据。", window.Label()))
+		return
+	}
+	rankList := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		rankList = append(rankList, fmt.Sprintf("Rank %d:%s(消息数：%d)", entry.Rank, entry.Username, entry.Score))
+	}
+	ctx.Reply(fmt.Sprintf("--- %s活跃度排名前 %d 用户 ---\n%s\n--- 排名结束 ---", window.Label(), len(rankList), strings.Join(rankList, "\n")))
+}
+
+// replyOwnRank 回复用户在各时间窗口中的名次
+func (s *Server) replyOwnRank(ctx *CommandContext) {
+	lines := []string{fmt.Sprintf("--- %s 的活跃度排名 ---", ctx.Name)}
+	for _, window := range rdb.RankWindows {
+		entry, found, err := s.asyncQueue.GetUserRank(window, ctx.Name)
+		switch {
+		case err != nil:
+			lines = append(lines, fmt.Sprintf("%s榜: 查询失败（%v）", window.Label(), err))
+		case !found:
+			lines = append(lines, fmt.Sprintf("%s榜: 暂无记录", window.Label()))
+		default:
+			lines = append(lines, fmt.Sprintf("%s榜: 第 %d 名（消息数：%d）", window.Label(), entry.Rank, entry.Score))
+		}
+	}
+	lines = append(lines, "--- 排名结束 ---")
+	ctx.Reply(strings.Join(lines, "\n"))
+}
+
+// cmdInfo 处理 /info 命令，显示服务器连接状态和当前生效的保留策略
+func (s *Server) cmdInfo(ctx *CommandContext) {
+	s.mutex.RLock()
+	online := len(s.clients)
+	s.mutex.RUnlock()
+
+	lines := []string{
+		"--- 服务器信息 ---",
+		fmt.Sprintf("在线用户: %d", online),
+	}
+	if s.userDB != nil {
+		lines = append(lines, "MySQL: 已连接")
+	} else {
+		lines = append(lines, "MySQL: 未连接")
+	}
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		lines = append(lines, "Redis: 未连接（历史记录和排名不可用）")
+	} else {
+		lines = append(lines, "Redis: 已连接")
+		if n, err := s.asyncQueue.ChatHistoryLen(); err == nil {
+			lines = append(lines, fmt.Sprintf("聊天历史: %d 条", n))
+		}
+		lines = append(lines, "历史保留策略: "+s.asyncQueue.Retention.String())
+		if s.janitorInterval > 0 {
+			lines = append(lines, fmt.Sprintf("历史清理间隔: %s", s.janitorInterval))
+		} else {
+			lines = append(lines, "历史清理间隔: 已禁用")
+		}
+	}
+	lines = append(lines, "--- 信息结束 ---")
+	ctx.Reply(strings.Join(lines, "\n"))
+}
+
+// cmdStats 处理 /stats 命令，显示统计收集器的当前快照
+func (s *Server) cmdStats(ctx *CommandContext) {
+	snap := s.stats.Snapshot()
+	lines := []string{
+		"--- 服务器运行统计 ---",
+		fmt.Sprintf("运行时长: %s", snap.Uptime.Truncate(time.Second)),
+		fmt.Sprintf("消息速率: 最近1分钟 %d 条，最近1小时平均 %.1f 条/分钟", snap.MessagesLastMin, snap.MessagesPerMinAvg),
+		fmt.Sprintf("在线人数: 当前 %d，峰值 %d", snap.Online, snap.PeakOnline),
+		fmt.Sprintf("登录: 成功 %d 次，失败 %d 次", snap.LoginSuccess, snap.LoginFailure),
+		fmt.Sprintf("私聊消息: %d 条", snap.PrivateMessages),
+		fmt.Sprintf("Redis 入队失败: %d 次", snap.EnqueueFailures),
+		fmt.Sprintf("广播: %d 次，投递 %d 个连接，平均耗时 %s，最大 %s，最近 %s",
+			snap.FanoutCount, snap.FanoutRecipients, snap.FanoutAvg, snap.FanoutMax, snap.FanoutLast),
+	}
+	for _, msgType := range snap.MessageTypes() {
+		lines = append(lines, fmt.Sprintf("消息类型 %s: %d 条", msgType, snap.MessagesByType[msgType]))
+	}
+	lines = append(lines, "--- 统计结束 ---")
+	ctx.Reply(strings.Join(lines, "\n"))
+}
+
+// cmdAudit 处理 /audit 命令，显示最近的认证审计事件，可按用户名过滤
+func (s *Server) cmdAudit(ctx *CommandContext) {
+	const maxAuditEvents = 20
+	if s.userDB == nil {
+		ctx.Reply("系统：审计功能当前不可用（数据库未连接）")
+		return
+	}
+	var user string
+	if len(ctx.Args) > 0 {
+		user = ctx.Args[0]
+	}
+	events, err := s.userDB.QueryAuthEvents(user, maxAuditEvents)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：查询审计记录失败：%v", err))
+		return
+	}
+	if len(events) == 0 {
+		ctx.Reply("系统：暂无审计记录")
+		return
+	}
+
+	lines := make([]string, 0, len(events))
+	for _, ev := range events {
+		line := fmt.Sprintf("[%s] %s %s %s %s", ev.Time.Format("2006-01-02 15:04:05"), ev.Username, ev.RemoteIP, ev.Event, ev.Outcome)
+		if ev.Reason != "" {
+			line += "（" + ev.Reason + "）"
+		}
+		lines = append(lines, line)
+	}
+	ctx.Reply(fmt.Sprintf("--- 最近 %d 条审计记录 ---\n%s\n--- 审计记录结束 ---", len(lines), strings.Join(lines, "\n")))
+}
+
+// cmdExit 处理 /exit 命令，关闭连接后由 handleClientChat 负责注销
+func (s *Server) cmdExit(ctx *CommandContext) {
+	ctx.Reply("再见！")
+	ctx.Conn.Close()
+}
+
+// cmdUnlock 处理 /unlock 命令，解除账号或 IP 的登录锁定
+// /unlock <用户名> 只解除账号锁定，/unlock ip <地址> 解除 IP 锁定
+func (s *Server) cmdUnlock(ctx *CommandContext) {
+	if !s.loginGuardEnabled() {
+		ctx.Reply("系统：登录锁定功能当前不可用（Redis未连接）")
+		return
+	}
+	if len(ctx.Args) == 2 {
+		if ctx.Args[0] != "ip" {
+			ctx.Reply("用法：/unlock <用户名> 或 /unlock ip <地址>")
+			return
+		}
+		s.unlockIP(ctx, ctx.Args[1])
+		return
+	}
+
+	target := nickname.Normalize(ctx.Args[0])
+	wasLocked, err := s.asyncQueue.UnlockUser(target)
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：解除锁定失败：%v", err))
+		return
+	}
+	slog.Info("管理员解除账号锁定", "user", target, "admin", ctx.Name, "was_locked", wasLocked)
+	s.recordAuthEvent(ctx.Conn, db.AuthEventUnlock, target, db.AuthOutcomeSuccess, "管理员 "+ctx.Name+" 解除锁定")
+	if wasLocked {
+		ctx.Reply(fmt.Sprintf("系统：已解除用户 '%s' 的登录锁定。IP 锁定不受影响，如仍无法登录，请使用 /unlock ip <地址> 解除", target))
+	} else {
+		ctx.Reply(fmt.Sprintf("系统：用户 '%s' 未被锁定，已清空其登录失败计数。如其 IP 被锁定，请使用 /unlock ip <地址> 解除", target))
+	}
+}
+
+// unlockIP 解除 IP 的登录锁定并清空其失败计数
+func (s *Server) unlockIP(ctx *CommandContext, addr string) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		ctx.Reply(fmt.Sprintf("系统：'%s' 不是有效的 IP 地址", addr))
+		return
+	}
+	wasLocked, err := s.asyncQueue.UnlockIP(ip.String())
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("系统：解除锁定失败：%v", err))
+		return
+	}
+	slog.Info("管理员解除IP锁定", "ip", ip.String(), "admin", ctx.Name, "was_locked", wasLocked)
+	s.recordAuthEvent(ctx.Conn, db.AuthEventUnlock, "", db.AuthOutcomeSuccess, "管理员 "+ctx.Name+" 解除 IP "+ip.String()+" 的锁定")
+	if wasLocked {
+		ctx.Reply(fmt.Sprintf("系统：已解除 IP %s 的登录锁定", ip))
+	} else {
+		ctx.Reply(fmt.Sprintf("系统：IP %s 未被锁定，已清空其登录失败计数", ip))
+	}
+}
+
+// cmdLogout 处理 /logout 命令，吊销当前会话令牌后关闭连接
+func (s *Server) cmdLogout(ctx *CommandContext) {
+	if _, err := s.revokeSession(ctx.Conn); err != nil {
+		slog.Error("吊销会话令牌失败", "user", ctx.Name, "error", err)
+		ctx.Reply("系统：吊销会话令牌失败，令牌将在过期后自动失效。已断开连接")
+		ctx.Conn.Close()
+		return
+	}
+	ctx.Reply("已退出登录，会话令牌已失效。再见！")
+	ctx.Conn.Close()
+}
+
+// unknownCommandMessage 生成未知命令的提示，并尽量给出相近命令的建议
+func (s *Server) unknownCommandMessage(name string, role Role) string {
+	if suggestion := s.commands.Suggest(name, role); suggestion != "" {
+		return fmt.Sprintf("未知命令 %s，您是不是想输入 %s？使用 /help 查看可用命令", name, suggestion)
+	}
+	return "未知命令，使用 /help 查看可用命令"
+}
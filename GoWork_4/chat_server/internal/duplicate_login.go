@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// DuplicateLoginPolicy 同一账号重复登录时的处理策略
+type DuplicateLoginPolicy string
+
+const (
+	DuplicateLoginReject   DuplicateLoginPolicy = "reject"   // 账号已在线时拒绝新的登录
+	DuplicateLoginTakeover DuplicateLoginPolicy = "takeover" // 新的登录确认后踢出旧会话
+	DuplicateLoginMulti    DuplicateLoginPolicy = "multi"    // 允许多个会话同时在线
+)
+
+// parseDuplicateLoginPolicy 解析配置中的重复登录策略，无法识别时使用 multi
+func parseDuplicateLoginPolicy(value string) DuplicateLoginPolicy {
+	switch policy := DuplicateLoginPolicy(value); policy {
+	case DuplicateLoginReject, DuplicateLoginTakeover, DuplicateLoginMulti:
+		return policy
+	default:
+		slog.Warn("未知的重复登录策略，使用默认值", "value", value, "default", DuplicateLoginMulti)
+		return DuplicateLoginMulti
+	}
+}
+
+// rejectsDuplicateLogin 判断是否因账号已在线而拒绝本次登录
+// 仅在 reject 策略下生效，takeover 策略在密码校验通过后由 confirmTakeover 询问
+// 各账号存储后端的用户名均按完全相同匹配，能通过登录的 name 就是注册时的写法，与 s.clients 的键一致
+func (s *Server) rejectsDuplicateLogin(name string) bool {
+	return s.duplicateLogin == DuplicateLoginReject && s.isNameTaken(name)
+}
+
+// rejectsDuplicateResume 判断是否因账号已在线而拒绝凭令牌恢复会话
+// 恢复会话由客户端自动发起，无法询问用户，因此 takeover 策略下按 reject 处理
+func (s *Server) rejectsDuplicateResume(name string) bool {
+	return s.duplicateLogin != DuplicateLoginMulti && s.isNameTaken(name)
+}
+
+// confirmTakeover 在 takeover 策略下询问密码校验通过的新登录是否顶替已在线的会话
+// 用户拒绝时按 reject 策略处理，原会话保持在线
+// 返回值 proceed 表示继续登录，disconnected 表示等待回答时客户端已断开
+func (s *Server) confirmTakeover(conn net.Conn, name string) (proceed, disconnected bool) {
+	if s.duplicateLogin != DuplicateLoginTakeover || !s.isNameTaken(name) {
+		return true, false
+	}
+	if err := tools.SendMessage(conn, fmt.Sprintf("账号 '%s' 已在其他地方登录，%s？(y/n)", name, tools.TakeoverPrompt)); err != nil {
+		return false, true
+	}
+	answer, err := tools.ReceiveMessage(conn)
+	if err != nil {
+		return false, true
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "是":
+		return true, false
+	}
+	s.recordAuthEvent(conn, db.AuthEventLogin, name, db.AuthOutcomeFailure, "昵称已在线，未顶替原会话")
+	tools.SendMessage(conn, "已取消登录，原会话保持在线，请返回主菜单。")
+	return false, false
+}
+
+// takeOverSessions 在 takeover 策略下踢出用户除 conn 以外的所有会话
+// 调用前新登录已经通过 confirmTakeover 确认；调用时 conn 已经注册，因此旧会话断开时不会广播下线消息
+// 被踢出的会话令牌同时吊销，防止旧客户端凭令牌重新登录后反过来踢掉新会话
+func (s *Server) takeOverSessions(conn net.Conn, name string) {
+	if s.duplicateLogin != DuplicateLoginTakeover {
+		return
+	}
+	kicked := 0
+	for _, old := range s.getClientConnections(name) {
+		if old == conn {
+			continue
+		}
+		if _, err := s.revokeSession(old); err != nil {
+			slog.Warn("吊销被踢出会话的令牌失败", "user", name, "remote_addr", old.RemoteAddr().String(), "error", err)
+		}
+		tools.SendMessage(old, tools.TakeoverNotice)
+		s.unregisterChan <- old
+		kicked++
+	}
+	if kicked > 0 {
+		slog.Info("账号在别处登录，已踢出旧会话", "user", name, "remote_addr", conn.RemoteAddr().String(), "kicked", kicked)
+	}
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/auth"
+	"GoWork_4/chat_server/session"
+	"GoWork_4/chat_server/stats"
+	"GoWork_4/tools"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testBcryptCost 测试使用最低的 bcrypt 代价，加快哈希计算
+const testBcryptCost = bcrypt.MinCost
+
+// newTestServer 创建使用内存账号存储、不连接 MySQL 和 Redis 的服务器，并在本机随机端口上接受连接
+// 返回服务器和用于建立新客户端连接的函数
+func newTestServer(t *testing.T, policy DuplicateLoginPolicy) (*Server, func() net.Conn) {
+	t.Helper()
+	s := &Server{
+		clients:          make(map[string][]net.Conn),
+		clientConnToName: make(map[net.Conn]string),
+		connectedAt:      make(map[net.Conn]time.Time),
+		messageChan:      make(chan *ClientMessage, 100),
+		broadcastChan:    make(chan *ClientMessage, 100),
+		registerChan:     make(chan net.Conn, 10),
+		unregisterChan:   make(chan net.Conn, 10),
+		Done:             make(chan struct{}),
+		commands:         newCommandRegistry(),
+		admins:           make(map[string]bool),
+		stats:            stats.NewCollector(),
+		sessions:         make(map[net.Conn]*session.Claims),
+		users:            auth.NewMemoryStore(testBcryptCost),
+		duplicateLogin:   policy,
+	}
+	s.registerBuiltinCommands()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败：%v", err)
+	}
+	t.Cleanup(func() {
+		close(s.Done)
+		listener.Close()
+	})
+	go s.handleMessages()
+	go s.handleBroadcasts()
+	go s.acceptConnections(listener)
+
+	return s, func() net.Conn {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("连接服务器失败：%v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+}
+
+// expectMessage 读取服务器消息，直到收到包含 want 的消息，超时则测试失败
+func expectMessage(t *testing.T, conn net.Conn, want string) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		msg, err := tools.ReceiveMessage(conn)
+		if err != nil {
+			t.Fatalf("等待包含 %q 的消息失败：%v", want, err)
+		}
+		if strings.Contains(msg, want) {
+			return msg
+		}
+	}
+}
+
+// expectNoMessage 在 wait 内读取服务器消息，收到包含 unwanted 的消息时测试失败
+func expectNoMessage(t *testing.T, conn net.Conn, unwanted string, wait time.Duration) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(wait))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		msg, err := tools.ReceiveMessage(conn)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return
+		}
+		if err != nil {
+			t.Fatalf("读取消息失败：%v", err)
+		}
+		if strings.Contains(msg, unwanted) {
+			t.Fatalf("不应收到包含 %q 的消息：%s", unwanted, msg)
+		}
+	}
+}
+
+// login 在新连接上以 name 和 password 登录，直到看到进入聊天室的提示
+func login(t *testing.T, dial func() net.Conn, name, password string) net.Conn {
+	t.Helper()
+	conn := dial()
+	expectMessage(t, conn, "请选择操作")
+	tools.SendMessage(conn, "1")
+	expectMessage(t, conn, "请输入昵称")
+	tools.SendMessage(conn, name)
+	expectMessage(t, conn, "请输入密码")
+	tools.SendMessage(conn, password)
+	expectMessage(t, conn, "开始聊天")
+	return conn
+}
+
+func TestCaseVariantLoginDoesNotBypassDuplicatePolicy(t *testing.T) {
+	for _, policy := range []DuplicateLoginPolicy{DuplicateLoginReject, DuplicateLoginTakeover, DuplicateLoginMulti} {
+		t.Run(string(policy), func(t *testing.T) {
+			s, dial := newTestServer(t, policy)
+			if err := s.users.Register("bob", "secret1"); err != nil {
+				t.Fatalf("注册失败：%v", err)
+			}
+			first := login(t, dial, "bob", "secret1")
+
+			// 用户名按注册时的写法完全匹配，大小写不同的昵称不能登录到同一账号
+			for _, variant := range []string{"BOB", "Bob", "bOb"} {
+				conn := dial()
+				expectMessage(t, conn, "请选择操作")
+				tools.SendMessage(conn, "1")
+				expectMessage(t, conn, "请输入昵称")
+				tools.SendMessage(conn, variant)
+				expectMessage(t, conn, "未注册")
+			}
+
+			expectNoMessage(t, first, tools.TakeoverNotice, 100*time.Millisecond)
+			s.mutex.RLock()
+			defer s.mutex.RUnlock()
+			if len(s.clients) != 1 || len(s.clients["bob"]) != 1 {
+				t.Fatalf("在线会话不符合预期：%v", s.clients)
+			}
+		})
+	}
+}
+
+func TestDuplicateLoginPolicies(t *testing.T) {
+	tests := []struct {
+		policy   DuplicateLoginPolicy
+		answer   string // takeover 策略下对确认提示的回答
+		sessions int    // 第二次登录后 bob 的在线会话数
+		kicked   bool   // 第一个会话是否被踢出
+	}{
+		{DuplicateLoginMulti, "", 2, false},
+		{DuplicateLoginTakeover, "y", 1, true},
+		{DuplicateLoginTakeover, "n", 1, false},
+		{DuplicateLoginReject, "", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+tt.answer, func(t *testing.T) {
+			s, dial := newTestServer(t, tt.policy)
+			if err := s.users.Register("bob", "secret1"); err != nil {
+				t.Fatalf("注册失败：%v", err)
+			}
+			first := login(t, dial, "bob", "secret1")
+
+			second := dial()
+			expectMessage(t, second, "请选择操作")
+			tools.SendMessage(second, "1")
+			expectMessage(t, second, "请输入昵称")
+			tools.SendMessage(second, "bob")
+			switch tt.policy {
+			case DuplicateLoginReject:
+				expectMessage(t, second, "已在线")
+			case DuplicateLoginTakeover:
+				expectMessage(t, second, "请输入密码")
+				tools.SendMessage(second, "secret1")
+				expectMessage(t, second, tools.TakeoverPrompt)
+				tools.SendMessage(second, tt.answer)
+				if tt.kicked {
+					expectMessage(t, second, "开始聊天")
+				} else {
+					expectMessage(t, second, "原会话保持在线")
+				}
+			default:
+				expectMessage(t, second, "请输入密码")
+				tools.SendMessage(second, "secret1")
+				expectMessage(t, second, "开始聊天")
+			}
+
+			if tt.kicked {
+				expectMessage(t, first, tools.TakeoverNotice)
+			} else {
+				expectNoMessage(t, first, tools.TakeoverNotice, 100*time.Millisecond)
+			}
+			// 被踢出的会话经由 unregisterChan 异步移除
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				s.mutex.RLock()
+				n := len(s.clients["bob"])
+				s.mutex.RUnlock()
+				if n == tt.sessions {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("bob 的在线会话数为 %d，期望 %d", n, tt.sessions)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/auth"
+	"context"
+	"net/http"
+	"time"
+)
+
+// 就绪检查中各项依赖的状态
+const (
+	checkOK          = "ok"
+	checkMissing     = "missing"     // 启动时未能连接，相关功能已禁用
+	checkUnreachable = "unreachable" // 已连接但当前无法访问
+)
+
+// handleHealthz 处理 /healthz 存活检查，进程能响应即返回 200
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz 处理 /readyz 就绪检查
+// TCP 监听是登录聊天的必要条件，异常时返回 503；账号存储在 MySQL 中（CHAT_AUTH_BACKEND=mysql）时 MySQL 同样必要。
+// 使用文件或内存账号存储时 MySQL 只影响归档、搜索、资料等功能，与 Redis 缺失一样返回 200 并标记为 degraded。
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := map[string]string{
+		"listener": checkOK,
+		"mysql":    checkOK,
+		"redis":    checkOK,
+	}
+	if !s.listening.Load() {
+		checks["listener"] = "not_accepting"
+	}
+	if s.userDB == nil || s.userDB.DB == nil {
+		checks["mysql"] = checkMissing
+	} else if err := s.userDB.DB.PingContext(ctx); err != nil {
+		checks["mysql"] = checkUnreachable
+	}
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		checks["redis"] = checkMissing
+	} else if err := s.asyncQueue.Client.Ping(ctx).Err(); err != nil {
+		checks["redis"] = checkUnreachable
+	}
+
+	_, mysqlRequired := s.users.(*auth.MySQLStore)
+	status, code := "ready", http.StatusOK
+	switch {
+	case checks["listener"] != checkOK || (mysqlRequired && checks["mysql"] != checkOK):
+		status, code = "not_ready", http.StatusServiceUnavailable
+	case checks["mysql"] != checkOK || checks["redis"] != checkOK:
+		status = "degraded"
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": checks})
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// startHTTPServer 启动可选的 HTTP 监听
+// 参数 addr 是监听地址，如 ":8080"
+func (s *Server) startHTTPServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	if s.adminToken != "" {
+		s.registerAdminAPI(mux)
+	} else {
+		slog.Info("未设置 CHAT_ADMIN_TOKEN，HTTP 管理 API 未启用")
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		slog.Info("HTTP 服务已启动", "addr", addr)
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("HTTP 服务启动失败", "addr", addr, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/rdb"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// loginGuardEnabled 判断防暴力破解功能是否可用，失败计数依赖 Redis
+func (s *Server) loginGuardEnabled() bool {
+	return s.asyncQueue != nil && s.asyncQueue.Client != nil
+}
+
+// rejectIfLocked 检查账号或连接 IP 是否处于锁定期，锁定时通知客户端并记录审计事件
+// 返回值表示是否已拒绝本次登录
+func (s *Server) rejectIfLocked(conn net.Conn, name string) bool {
+	remaining, locked := s.loginLockout(conn, name)
+	if !locked {
+		return false
+	}
+	slog.Warn("账号或 IP 处于锁定期，拒绝登录", "user", name, "remote_addr", conn.RemoteAddr().String(), "remaining", remaining)
+	s.recordAuthEvent(conn, db.AuthEventLogin, name, db.AuthOutcomeFailure, "账号或IP已锁定")
+	tools.SendMessage(conn, fmt.Sprintf("登录失败次数过多，账号或IP已被临时锁定，请 %s 后再试，请返回主菜单。", formatLockout(remaining)))
+	return true
+}
+
+// loginLockout 查询账号或连接 IP 是否处于锁定期
+// 返回值 remaining 是剩余锁定时长，locked 表示是否锁定
+func (s *Server) loginLockout(conn net.Conn, name string) (time.Duration, bool) {
+	if !s.loginGuardEnabled() {
+		return 0, false
+	}
+	remaining, locked, err := s.asyncQueue.LoginLockout(name, remoteIP(conn))
+	if err != nil {
+		// 查询失败时放行，避免 Redis 故障导致所有用户无法登录
+		slog.Error("查询登录锁定状态失败", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
+		return 0, false
+	}
+	return remaining, locked
+}
+
+// recordLoginFailure 记录一次密码错误，按失败次数渐进延迟，达到阈值时锁定
+// 返回值表示本次失败是否触发了锁定（已通知客户端）
+func (s *Server) recordLoginFailure(conn net.Conn, name string) bool {
+	result := s.countLoginFailure(conn, name)
+	if result == nil || !result.Locked {
+		return false
+	}
+	tools.SendMessage(conn, fmt.Sprintf("登录失败，密码不正确。失败次数过多，账号或IP已被临时锁定，请 %s 后再试，请返回主菜单。", formatLockout(result.LockedFor)))
+	return true
+}
+
+// countLoginFailure 累计一次密码错误，触发锁定时记录审计事件，未锁定时按失败次数渐进延迟
+// 登录和敏感命令的密码确认共用同一套计数，返回值为 nil 表示防暴力破解功能不可用
+func (s *Server) countLoginFailure(conn net.Conn, name string) *rdb.LoginFailure {
+	if !s.loginGuardEnabled() {
+		return nil
+	}
+	result, err := s.asyncQueue.RecordLoginFailure(name, remoteIP(conn))
+	if err != nil {
+		slog.Error("记录登录失败次数失败", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
+		return nil
+	}
+	if result.Locked {
+		slog.Warn("登录失败次数过多，已锁定", "user", name, "remote_addr", conn.RemoteAddr().String(),
+			"user_failures", result.UserFailures, "ip_failures", result.IPFailures, "lockout", result.LockedFor)
+		s.recordAuthEvent(conn, db.AuthEventLockout, name, db.AuthOutcomeFailure,
+			fmt.Sprintf("账号失败 %d 次，IP 失败 %d 次", result.UserFailures, result.IPFailures))
+		return result
+	}
+	// 渐进延迟：失败越多等待越久，拖慢在线猜测密码的速度
+	failures := max(result.UserFailures, result.IPFailures)
+	time.Sleep(s.asyncQueue.LoginGuard.Delay(failures))
+	return result
+}
+
+// clearLoginFailures 登录成功后清空账号的失败计数
+func (s *Server) clearLoginFailures(name string) {
+	if !s.loginGuardEnabled() {
+		return
+	}
+	if err := s.asyncQueue.ClearLoginFailures(name); err != nil {
+		slog.Warn("清空登录失败次数失败", "user", name, "error", err)
+	}
+}
+
+// formatLockout 将锁定时长格式化为便于阅读的形式，不足一秒按一秒计
+func formatLockout(d time.Duration) string {
+	return max(d.Round(time.Second), time.Second).String()
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/rdb"
+	"GoWork_4/chat_server/stats"
+	"net/http"
+)
+
+// handleMetrics 以 Prometheus 文本格式输出服务器指标
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p := stats.NewPromWriter(w)
+	p.WriteSnapshot(s.stats.Snapshot())
+
+	// 内部通道积压情况
+	p.Gauge("chat_queue_depth", "内部消息通道当前积压的消息数", float64(len(s.messageChan)), "queue", "message")
+	p.Gauge("chat_queue_depth", "内部消息通道当前积压的消息数", float64(len(s.broadcastChan)), "queue", "broadcast")
+	p.Gauge("chat_queue_capacity", "内部消息通道容量", float64(cap(s.messageChan)), "queue", "message")
+	p.Gauge("chat_queue_capacity", "内部消息通道容量", float64(cap(s.broadcastChan)), "queue", "broadcast")
+
+	// Redis 消费者组积压情况
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		p.Gauge("chat_redis_up", "Redis 是否已连接", 0)
+		return
+	}
+	p.Gauge("chat_redis_up", "Redis 是否已连接", 1)
+	for _, group := range []string{rdb.ChatGroupKey, rdb.ArchiveGroupKey} {
+		info, err := s.asyncQueue.GetConsumerGroupInfo(group)
+		if err != nil {
+			continue
+		}
+		if info.Lag >= 0 {
+			p.Gauge("chat_redis_consumer_lag", "消费者组尚未读取的消息数", float64(info.Lag), "group", group)
+		}
+		p.Gauge("chat_redis_consumer_pending", "消费者组已读取但未确认的消息数", float64(info.Pending), "group", group)
+	}
+}
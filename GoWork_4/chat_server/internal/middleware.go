@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/config"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// MessageMiddleware 消息中间件
+// 每条 ClientMessage 在广播或入队之前都会依次经过已启用的中间件，
+// 包括用户和机器人发送的消息，以及上下线、加入聊天室等系统消息（Type 为 "system"）。
+// 系统消息只能经由 messageChan 发送，不要直接写入 broadcastChan 绕过中间件。
+// 返回值：
+//   - 返回消息（原样或修改后的）表示放行，后续中间件将收到该消息
+//   - 返回 nil 且 error 为 nil 表示静默丢弃该消息
+//   - 返回 error 表示否决该消息，错误内容会作为提示发送给消息发送者
+type MessageMiddleware func(msg *ClientMessage) (*ClientMessage, error)
+
+// MiddlewareFactory 根据服务器配置构造一个中间件实例
+type MiddlewareFactory func(cfg *config.Config) MessageMiddleware
+
+// middlewareRegistry 保存所有在代码中注册的中间件，键为中间件名称
+var middlewareRegistry = make(map[string]MiddlewareFactory)
+
+// RegisterMiddleware 注册一个可通过配置启用的中间件
+// 参数 name 是在 CHAT_MIDDLEWARES 中引用的名称，factory 用于构造中间件实例
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	middlewareRegistry[name] = factory
+}
+
+func init() {
+	RegisterMiddleware("profanity", newProfanityMiddleware)
+	RegisterMiddleware("maxlen", newMaxLenMiddleware)
+	RegisterMiddleware("audit", newAuditMiddleware)
+}
+
+// buildMiddlewareChain 按配置中的顺序构造中间件链，未注册的名称会被忽略并打印警告
+func buildMiddlewareChain(cfg *config.Config) []MessageMiddleware {
+	var chain []MessageMiddleware
+	for _, name := range cfg.Middlewares {
+		factory, ok := middlewareRegistry[name]
+		if !ok {
+			slog.Warn("未知的消息中间件，已忽略", "middleware", name)
+			continue
+		}
+		chain = append(chain, factory(cfg))
+		slog.Info("已启用消息中间件", "middleware", name)
+	}
+	return chain
+}
+
+// applyMiddlewares 让消息依次通过中间件链
+// 返回值 msg 是处理后的消息，ok 为 false 表示消息已被否决或丢弃
+func (s *Server) applyMiddlewares(msg *ClientMessage) (*ClientMessage, bool) {
+	for _, mw := range s.middlewares {
+		next, err := mw(msg)
+		if err != nil {
+			if msg.Conn != nil {
+				tools.SendMessage(msg.Conn, fmt.Sprintf("【系统】消息未发送：%v", err))
+			}
+			return nil, false
+		}
+		if next == nil {
+			return nil, false
+		}
+		msg = next
+	}
+	return msg, true
+}
+
+// annotate 为消息附加一条中间件注解
+func (m *ClientMessage) annotate(key, value string) {
+	if m.Meta == nil {
+		m.Meta = make(map[string]string)
+	}
+	m.Meta[key] = value
+}
+
+// newProfanityMiddleware 敏感词过滤：将消息中的敏感词替换为等长的星号
+func newProfanityMiddleware(cfg *config.Config) MessageMiddleware {
+	words := cfg.ProfanityWords
+	return func(msg *ClientMessage) (*ClientMessage, error) {
+		if msg.Type == "system" {
+			return msg, nil
+		}
+		filtered := msg.Message
+		for _, word := range words {
+			filtered = strings.ReplaceAll(filtered, word, strings.Repeat("*", utf8.RuneCountInString(word)))
+		}
+		if filtered != msg.Message {
+			msg.Message = filtered
+			msg.annotate("profanity", "filtered")
+		}
+		return msg, nil
+	}
+}
+
+// newMaxLenMiddleware 消息长度限制：否决超过最大字符数的用户消息
+func newMaxLenMiddleware(cfg *config.Config) MessageMiddleware {
+	limit := cfg.MaxMessageLen
+	return func(msg *ClientMessage) (*ClientMessage, error) {
+		if msg.Type == "system" || limit <= 0 {
+			return msg, nil
+		}
+		if utf8.RuneCountInString(msg.Message) > limit {
+			return nil, fmt.Errorf("消息长度超过 %d 个字符", limit)
+		}
+		return msg, nil
+	}
+}
+
+// newAuditMiddleware 审计日志：记录每条经过中间件链的消息
+// Info 级别只记录消息类型、发送者、长度等元数据，消息正文（包括私聊内容）只在 Debug 级别记录
+func newAuditMiddleware(cfg *config.Config) MessageMiddleware {
+	return func(msg *ClientMessage) (*ClientMessage, error) {
+		slog.Info("消息审计", "msg_type", msg.Type, "user", msg.Name, "target", msg.Target, "meta", msg.Meta, "length", utf8.RuneCountInString(msg.Message))
+		slog.Debug("消息审计正文", "msg_type", msg.Type, "user", msg.Name, "target", msg.Target, "message", msg.Message)
+		return msg, nil
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/tools"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy 密码强度策略，注册和修改密码时校验
+type PasswordPolicy struct {
+	MinLength      int  // 最少字符数（按 Unicode 字符计）
+	MinClasses     int  // 至少包含的字符类别数：小写字母、大写字母、数字、符号
+	RejectUsername bool // 是否拒绝包含用户名的密码（不区分大小写）
+}
+
+// receivePassword 读取客户端输入的密码并去除首尾空白
+// 登录、注册、确认和修改密码时的每次输入都经由这里读取，与客户端 tools.ReadPassword 的处理一致，
+// 保证同一密码的多次输入按相同规则比较
+func receivePassword(conn net.Conn) (string, error) {
+	input, err := tools.ReceiveMessage(conn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// maxRegisterPasswordAttempts 注册时密码不符合要求或两次输入不一致的最多尝试次数
+const maxRegisterPasswordAttempts = 3
+
+// passwordClassNames 字符类别名称，顺序与 passwordClasses 的返回值一致
+var passwordClassNames = []string{"小写字母", "大写字母", "数字", "符号"}
+
+// Validate 校验密码是否符合策略
+// 参数 name 是密码所属的用户名，password 是待校验的密码
+// 返回值 valid 表示校验结果，reason 提供不符合要求的原因
+func (p PasswordPolicy) Validate(name, password string) (bool, string) {
+	if password == "" {
+		return false, "密码不能为空"
+	}
+	if len(password) > db.MaxPasswordBytes {
+		return false, fmt.Sprintf("密码长度不能超过%d个字节", db.MaxPasswordBytes)
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return false, fmt.Sprintf("密码长度不能少于%d个字符", p.MinLength)
+	}
+	if p.MinClasses > 0 {
+		if n := passwordClasses(password); n < p.MinClasses {
+			return false, fmt.Sprintf("密码至少需要包含%s中的%d类字符", strings.Join(passwordClassNames, "、"), p.MinClasses)
+		}
+	}
+	if p.RejectUsername && name != "" && strings.Contains(strings.ToLower(password), strings.ToLower(name)) {
+		return false, "密码不能包含用户名"
+	}
+	return true, ""
+}
+
+// Describe 返回策略的简要说明，用于提示用户输入密码
+func (p PasswordPolicy) Describe() string {
+	parts := []string{fmt.Sprintf("至少%d个字符", p.MinLength)}
+	if p.MinClasses > 0 {
+		parts = append(parts, fmt.Sprintf("包含%s中的至少%d类", strings.Join(passwordClassNames, "、"), p.MinClasses))
+	}
+	if p.RejectUsername {
+		parts = append(parts, "不能包含用户名")
+	}
+	return strings.Join(parts, "，")
+}
+
+// passwordClasses 统计密码中出现的字符类别数
+func passwordClasses(password string) int {
+	var seen [4]bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			seen[0] = true
+		case unicode.IsUpper(r):
+			seen[1] = true
+		case unicode.IsDigit(r):
+			seen[2] = true
+		default:
+			seen[3] = true
+		}
+	}
+	n := 0
+	for _, ok := range seen {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
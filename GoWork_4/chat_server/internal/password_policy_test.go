@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 3, RejectUsername: true}
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		user     string
+		password string
+		valid    bool
+	}{
+		{"符合要求", policy, "bob", "Secret-123", true},
+		{"空密码", policy, "bob", "", false},
+		{"长度不足", policy, "bob", "Ab1-", false},
+		{"只有两类字符", policy, "bob", "secret123", false},
+		{"小写大写数字三类", policy, "bob", "Secret123", true},
+		{"符号计为一类", policy, "bob", "secret-123", true},
+		{"非 ASCII 字符计为符号", policy, "bob", "secret密码1", true},
+		{"包含用户名", policy, "bob", "Bob-12345", false},
+		{"不检查用户名时允许包含", PasswordPolicy{MinLength: 8, MinClasses: 3}, "bob", "Bob-12345", true},
+		{"按字符而不是字节计长度", PasswordPolicy{MinLength: 4}, "bob", "密码", false},
+		{"正好 72 字节", PasswordPolicy{MinLength: 8}, "bob", strings.Repeat("a", db.MaxPasswordBytes), true},
+		{"超过 72 字节", PasswordPolicy{MinLength: 8}, "bob", strings.Repeat("a", db.MaxPasswordBytes+1), false},
+		{"多字节字符超过 72 字节", PasswordPolicy{MinLength: 8}, "bob", strings.Repeat("密", 25), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, reason := tt.policy.Validate(tt.user, tt.password)
+			if valid != tt.valid {
+				t.Fatalf("Validate(%q, %q) = (%v, %q)，期望 %v", tt.user, tt.password, valid, reason, tt.valid)
+			}
+			if !valid && reason == "" {
+				t.Fatal("校验失败时应给出原因")
+			}
+		})
+	}
+}
+
+func TestPasswordClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"ABC", 1},
+		{"123", 1},
+		{"!@#", 1},
+		{"aB", 2},
+		{"aB1", 3},
+		{"aB1!", 4},
+		{"Ünïcödé1", 3},
+	}
+	for _, tt := range tests {
+		if got := passwordClasses(tt.password); got != tt.want {
+			t.Errorf("passwordClasses(%q) = %d，期望 %d", tt.password, got, tt.want)
+		}
+	}
+}
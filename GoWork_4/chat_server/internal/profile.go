@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/chat_server/nickname"
+	"GoWork_4/chat_server/rdb"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+	_ "time/tzdata" // 运行镜像基于 alpine，不带时区数据库，内嵌一份用于校验和换算时区
+	"unicode"
+	"unicode/utf8"
+)
+
+// profileField 可通过 /profile set 修改的资料字段
+type profileField struct {
+	Column string // user_profiles 表中的列名，见 db.Profile* 常量
+	Label  string // 中文名称
+	MaxLen int    // 最多字符数
+}
+
+// profileFields 资料字段名（含别名）到字段定义的映射
+var profileFields = map[string]profileField{
+	"name":     {db.ProfileDisplayName, "显示名称", 32},
+	"bio":      {db.ProfileBio, "简介", 200},
+	"pronouns": {db.ProfilePronouns, "代词", 32},
+	"timezone": {db.ProfileTimeZone, "时区", 64},
+	"tz":       {db.ProfileTimeZone, "时区", 64},
+}
+
+// profileUsage /profile 命令的用法说明
+const profileUsage = "用法：/profile 查看自己的资料；/profile set <name|bio|pronouns|timezone> [内容] 修改资料，内容为空表示清除"
+
+// cmdProfile 处理 /profile 命令
+// 不带参数时显示自己的资料，/profile set <字段> [内容] 修改自己的资料
+func (s *Server) cmdProfile(ctx *CommandContext) {
+	if len(ctx.Args) == 0 {
+		ctx.Reply(s.whoisText(ctx.Name))
+		return
+	}
+	if ctx.Args[0] != "set" || len(ctx.Args) < 2 {
+		ctx.Reply(profileUsage)
+		return
+	}
+	if s.userDB == nil {
+		ctx.Reply("系统：资料功能当前不可用（数据库未连接）")
+		return
+	}
+
+	field, ok := profileFields[strings.ToLower(ctx.Args[1])]
+	if !ok {
+		ctx.Reply(fmt.Sprintf("系统：未知的资料字段 '%s'。%s", ctx.Args[1], profileUsage))
+		return
+	}
+	value, reason := s.validateProfileValue(ctx.Name, field, strings.Join(ctx.Args[2:], " "))
+	if reason != "" {
+		ctx.Reply(fmt.Sprintf("系统：%s无效：%s", field.Label, reason))
+		return
+	}
+	if err := s.userDB.SetProfileField(ctx.Name, field.Column, value); err != nil {
+		slog.Error("更新用户资料失败", "user", ctx.Name, "field", field.Column, "error", err)
+		ctx.Reply("系统：更新资料失败，请稍后重试")
+		return
+	}
+	if value == "" {
+		ctx.Reply(fmt.Sprintf("系统：已清除%s", field.Label))
+		return
+	}
+	ctx.Reply(fmt.Sprintf("系统：%s已更新为：%s", field.Label, value))
+}
+
+// validateProfileValue 校验资料字段的内容
+// 返回值 value 是规范化后的内容，reason 不为空表示内容无效
+func (s *Server) validateProfileValue(name string, field profileField, value string) (string, string) {
+	value = nickname.Normalize(value)
+	if value == "" {
+		return "", ""
+	}
+	if utf8.RuneCountInString(value) > field.MaxLen {
+		return "", fmt.Sprintf("不能超过%d个字符", field.MaxLen)
+	}
+	for _, char := range value {
+		if unicode.IsControl(char) {
+			return "", "包含非法字符"
+		}
+	}
+
+	switch field.Column {
+	case db.ProfileTimeZone:
+		loc, err := time.LoadLocation(value)
+		if err != nil || value == "Local" {
+			return "", "请使用 IANA 时区名称，如 Asia/Shanghai"
+		}
+		value = loc.String()
+	case db.ProfileDisplayName:
+		// 显示名称不能冒充其他用户
+		if similar, found, err := s.users.FindConfusable(value); err != nil {
+			return "", "检查相似用户名失败，请稍后重试"
+		} else if found && similar != name {
+			return "", fmt.Sprintf("与用户 '%s' 过于相似", similar)
+		}
+		if similar, ok := s.findConfusableOnline(value); ok && similar != name {
+			return "", fmt.Sprintf("与在线用户 '%s' 过于相似", similar)
+		}
+	}
+	return value, ""
+}
+
+// cmdWhois 处理 /whois 命令，显示指定用户的资料和在线状态
+func (s *Server) cmdWhois(ctx *CommandContext) {
+	ctx.Reply(s.whoisText(nickname.Normalize(ctx.Args[0])))
+}
+
+// whoisText 生成用户资料的展示文本，包括资料、在线状态、连接时长和活跃度
+func (s *Server) whoisText(name string) string {
+	conns := s.getClientConnections(name)
+	online := len(conns) > 0
+	isBot := online && isBotConn(conns[0])
+	registered, err := s.users.Exists(name)
+	if err != nil {
+		slog.Error("查询用户是否存在失败", "user", name, "error", err)
+	}
+	if err == nil && !registered && !online {
+		return fmt.Sprintf("系统：用户 '%s' 不存在", name)
+	}
+
+	lines := []string{fmt.Sprintf("--- 用户 %s 的资料 ---", name)}
+	switch {
+	case isBot:
+		lines = append(lines, "身份: 机器人")
+	case err != nil:
+		lines = append(lines, "资料: 查询失败")
+	case registered:
+		lines = append(lines, s.profileLines(name)...)
+	}
+
+	switch {
+	case len(conns) > 1:
+		lines = append(lines, fmt.Sprintf("状态: 在线（%d 个会话，最早的已连接 %s）", len(conns), s.connectedFor(conns[0])))
+	case online:
+		lines = append(lines, fmt.Sprintf("状态: 在线（已连接 %s）", s.connectedFor(conns[0])))
+	default:
+		lines = append(lines, "状态: 离线")
+	}
+	lines = append(lines, fmt.Sprintf("活跃度: %s", s.activityText(name)))
+	lines = append(lines, "--- 资料结束 ---")
+	return strings.Join(lines, "\n")
+}
+
+// profileLines 返回用户资料各字段的展示行，资料存储在 MySQL 中
+func (s *Server) profileLines(name string) []string {
+	if s.userDB == nil {
+		return []string{"资料: 不可用（数据库未连接）"}
+	}
+	profile, err := s.userDB.GetProfile(name)
+	if err != nil {
+		slog.Error("查询用户资料失败", "user", name, "error", err)
+		return []string{"资料: 查询失败"}
+	}
+	lines := []string{
+		fmt.Sprintf("显示名称: %s", valueOrUnset(profile.DisplayName)),
+		fmt.Sprintf("代词: %s", valueOrUnset(profile.Pronouns)),
+		fmt.Sprintf("时区: %s", formatTimeZone(profile.TimeZone)),
+		fmt.Sprintf("简介: %s", valueOrUnset(profile.Bio)),
+	}
+	if !profile.RegisteredAt.IsZero() {
+		lines = append(lines, fmt.Sprintf("注册时间: %s", profile.RegisteredAt.Format("2006-01-02")))
+	}
+	return lines
+}
+
+// connectedFor 返回连接自登录以来的时长，精确到秒
+func (s *Server) connectedFor(conn net.Conn) time.Duration {
+	s.mutex.RLock()
+	since, ok := s.connectedAt[conn]
+	s.mutex.RUnlock()
+	if !ok {
+		return 0
+	}
+	return time.Since(since).Round(time.Second)
+}
+
+// activityText 返回用户在总活跃度榜中的名次和消息数
+func (s *Server) activityText(name string) string {
+	if s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		return "不可用（Redis未连接）"
+	}
+	entry, found, err := s.asyncQueue.GetUserRank(rdb.RankAll, name)
+	switch {
+	case err != nil:
+		slog.Warn("查询用户活跃度失败", "user", name, "error", err)
+		return "查询失败"
+	case !found:
+		return "暂无记录"
+	default:
+		return fmt.Sprintf("总榜第 %d 名（消息数：%d）", entry.Rank, entry.Score)
+	}
+}
+
+// formatTimeZone 显示时区及该时区的当前时间
+func formatTimeZone(tz string) string {
+	if tz == "" {
+		return valueOrUnset(tz)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return tz
+	}
+	return fmt.Sprintf("%s（当地时间 %s）", tz, time.Now().In(loc).Format("15:04"))
+}
+
+// valueOrUnset 资料字段为空时显示"未设置"
+func valueOrUnset(value string) string {
+	if value == "" {
+		return "未设置"
+	}
+	return value
+}
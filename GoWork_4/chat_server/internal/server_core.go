@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"GoWork_4/chat_server/db"
 	"GoWork_4/chat_server/rdb"
 	"GoWork_4/tools"
+	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"strings"
 	"time"
@@ -16,18 +19,29 @@ func (s *Server) ChatTaskHandler(msg *rdb.ChatMessage) {
 		Message: msg.Message,
 		Type:    msg.Type,
 		Conn:    nil, // 消费者处理的消息不需要原始连接
+		ID:      msg.ID,
 	}
 
 	// 放入广播通道，由 handleBroadcasts 协程进行统一广播
 	s.broadcastChan <- clientMsg
 }
 
+// ArchiveTaskHandler 将从 Redis 消费到的聊天消息写入 MySQL 归档表
+func (s *Server) ArchiveTaskHandler(msg *rdb.ChatMessage) error {
+	return s.userDB.ArchiveMessage(&db.ArchivedMessage{
+		StreamID: msg.ID,
+		Sender:   msg.Name,
+		Content:  msg.Message,
+		SentAt:   msg.SentAt,
+	})
+}
+
 // Start 启动 TCP 服务器监听指定端口，并开启多个协程处理不同任务
 // 参数 port 是要监听的端口号字符串
 func (s *Server) Start(port string) {
 	listener, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		fmt.Printf("服务器启动失败: %v\n", err)
+		slog.Error("服务器启动失败", "port", port, "error", err)
 		return
 	}
 	defer listener.Close()
@@ -38,7 +52,7 @@ func (s *Server) Start(port string) {
 	if s.asyncQueue != nil {
 		// 检查并创建消费者组
 		if err := s.asyncQueue.CreateChatConsumerGroup(); err != nil {
-			fmt.Printf("警告：创建 Redis Stream 消费者组失败: %v\n", err)
+			slog.Warn("创建 Redis Stream 消费者组失败", "group", rdb.ChatGroupKey, "error", err)
 		}
 
 		// 2. 启动 3 个消费者
@@ -48,8 +62,26 @@ func (s *Server) Start(port string) {
 			s.asyncQueue.StartChatConsumer(consumerName, s.ChatTaskHandler)
 		}
 
+		// 3. 启动历史清理协程，按保留策略裁剪聊天历史
+		if s.janitorInterval > 0 {
+			s.asyncQueue.StartRetentionJanitor(s.janitorInterval, s.Done)
+		}
+
+		// 4. 启动归档消费者，将聊天消息持久化到 MySQL
+		if s.userDB != nil {
+			if err := s.asyncQueue.CreateArchiveConsumerGroup(); err != nil {
+				slog.Warn("创建归档消费者组失败", "group", rdb.ArchiveGroupKey, "error", err)
+			} else {
+				s.asyncQueue.StartArchiveConsumer("archive-consumer-1", s.ArchiveTaskHandler)
+			}
+		}
+
 	} else {
-		fmt.Println("警告：Redis异步队列未连接或初始化失败，异步任务功能将不可用")
+		slog.Warn("Redis 异步队列未连接，异步任务功能将不可用")
+	}
+	s.startBots()
+	if s.httpAddr != "" {
+		s.startHTTPServer(s.httpAddr)
 	}
 
 	<-s.Done
@@ -59,6 +91,8 @@ func (s *Server) Start(port string) {
 // 参数 listener 是已经建立好的监听器对象
 func (s *Server) acceptConnections(listener net.Listener) {
 	defer listener.Close()
+	s.listening.Store(true)
+	defer s.listening.Store(false)
 
 	for {
 		conn, err := listener.Accept()
@@ -67,7 +101,7 @@ func (s *Server) acceptConnections(listener net.Listener) {
 			case <-s.Done:
 				return
 			default:
-				fmt.Printf("接受连接失败: %v\n", err)
+				slog.Error("接受连接失败", "error", err)
 				time.Sleep(10 * time.Millisecond) // 避免忙等待
 				continue
 			}
@@ -86,15 +120,21 @@ func (s *Server) handleMessages() {
 			if msg == nil {
 				continue
 			}
+			msg, ok := s.applyMiddlewares(msg)
+			if !ok {
+				continue
+			}
+			s.stats.RecordMessage(msg.Type)
 			if msg.Type == "system" || msg.Type == "private" {
 				s.broadcastChan <- msg
 			} else {
 				// 普通聊天消息 (msg.Type == "chat")
 
 				// 1. 活跃度增加（同步操作，放在入队前） 🌟 新增活跃度逻辑
-				if s.asyncQueue != nil {
+				// 机器人的发言不计入活跃度，避免机器人出现在 /rank 排行榜中
+				if s.asyncQueue != nil && !s.isBotName(msg.Name) {
 					if err := s.asyncQueue.IncrUserAction(msg.Name); err != nil {
-						fmt.Printf("警告: 增加用户活跃度失败：%v\n", err)
+						slog.Warn("增加用户活跃度失败", "user", msg.Name, "error", err)
 					}
 				}
 
@@ -105,7 +145,8 @@ func (s *Server) handleMessages() {
 					Type:    msg.Type,
 				}
 				if err := s.asyncQueue.AsyncProduceMessage(chatMsg); err != nil {
-					fmt.Printf("警告：消息异步入队失败: %v，将尝试同步广播。\n", err)
+					s.stats.RecordEnqueueFailure()
+					slog.Warn("消息异步入队失败，改为同步广播", "user", msg.Name, "msg_type", msg.Type, "error", err)
 					// 入队失败回退：立即广播
 					s.broadcastChan <- msg
 				}
@@ -136,7 +177,14 @@ func (s *Server) handleAuthentication(conn net.Conn) {
 		if err != nil {
 			return
 		}
-		switch strings.TrimSpace(selection) {
+		selection = strings.TrimSpace(selection)
+		if token, ok := strings.CutPrefix(selection, tools.ResumeCommand+" "); ok {
+			if s.handleResume(conn, strings.TrimSpace(token)) {
+				return
+			}
+			continue
+		}
+		switch selection {
 		case "1":
 			if s.handleLogin(conn) {
 				return
@@ -166,67 +214,79 @@ func (s *Server) handleBroadcasts() {
 // broadcastMessage 实际将消息广播至所有在线客户端（包括私聊定向发送和连接清理）
 // 参数 clientMsg 是待广播的消息体
 func (s *Server) broadcastMessage(clientMsg *ClientMessage) {
+	start := time.Now()
 	s.mutex.RLock()
 
 	var connsToCleanup []net.Conn
+	recipients := 0
 
 	switch clientMsg.Type {
 	case "system":
 		broadcastMsg := clientMsg.Message
 
-		// 广播给所有客户端
-		for name, conn := range s.clients {
-			err := tools.SendMessage(conn, broadcastMsg)
-			if err != nil {
-				fmt.Printf("发送系统消息给 %s 失败，标记清理: %v\n", name, err)
-				connsToCleanup = append(connsToCleanup, conn)
+		// 广播给所有客户端的所有会话
+		for name, conns := range s.clients {
+			for _, conn := range conns {
+				recipients++
+				err := deliverMessage(conn, broadcastMsg, clientMsg)
+				if err != nil {
+					slog.Warn("发送系统消息失败，标记清理", "user", name, "msg_type", clientMsg.Type, "remote_addr", conn.RemoteAddr().String(), "error", err)
+					connsToCleanup = append(connsToCleanup, conn)
+				}
 			}
 		}
 
 	case "private":
-		// 1. 发送给目标用户 (Target)
-		targetConn, exists := s.clients[clientMsg.Target]
-		if exists {
-			// [私聊 - 张三 悄悄对你说]: 你好
-			msgToTarget := fmt.Sprintf("【私聊 - %s】: %s", clientMsg.Name, clientMsg.Message)
-			if err := tools.SendMessage(targetConn, msgToTarget); err != nil {
-				fmt.Printf("发送私聊消息给目标用户 %s 失败，标记清理: %v\n", clientMsg.Target, err)
+		// 1. 发送给目标用户 (Target) 的所有会话
+		// 在 handlePrivateMessage 中已经做了初步检查，但这里是最终发送点。如果目标突然离线，会在这里失效。
+		// [私聊 - 张三 悄悄对你说]: 你好
+		msgToTarget := fmt.Sprintf("【私聊 - %s】: %s", clientMsg.Name, clientMsg.Message)
+		for _, targetConn := range s.clients[clientMsg.Target] {
+			recipients++
+			if err := deliverMessage(targetConn, msgToTarget, clientMsg); err != nil {
+				slog.Warn("发送私聊消息失败，标记清理", "user", clientMsg.Target, "msg_type", clientMsg.Type, "remote_addr", targetConn.RemoteAddr().String(), "error", err)
 				connsToCleanup = append(connsToCleanup, targetConn)
 			}
-		} else {
-			// 在 handleMessages 中已经做了初步检查，但这里是最终发送点。如果目标突然离线，会在这里失效。
-			// 如果在 handleMessages 之前检查，此处可以省略，但为健壮性保留。
 		}
 
-		// 2. 发送确认给发送者 (Name)
-		senderConn, senderExists := s.clients[clientMsg.Name]
-		if senderExists {
-			// [私聊 - 你悄悄对 李四 说]: 你好
-			msgToSender := fmt.Sprintf("【私聊%s】: %s", clientMsg.Target, clientMsg.Message)
-			if err := tools.SendMessage(senderConn, msgToSender); err != nil {
-				fmt.Printf("发送私聊确认消息给发送者 %s 失败，标记清理: %v\n", clientMsg.Name, err)
+		// 2. 发送确认给发送者 (Name) 的所有会话，使各设备上的私聊记录保持一致
+		// [私聊 - 你悄悄对 李四 说]: 你好
+		msgToSender := fmt.Sprintf("【私聊%s】: %s", clientMsg.Target, clientMsg.Message)
+		for _, senderConn := range s.clients[clientMsg.Name] {
+			recipients++
+			if err := deliverMessage(senderConn, msgToSender, clientMsg); err != nil {
+				slog.Warn("发送私聊确认消息失败，标记清理", "user", clientMsg.Name, "msg_type", clientMsg.Type, "remote_addr", senderConn.RemoteAddr().String(), "error", err)
 				connsToCleanup = append(connsToCleanup, senderConn)
 			}
 		}
 
 	case "chat": // 普通聊天消息（可能来自同步的 handleMessages 失败回退，或来自异步的 ChatTaskHandler）
 		broadcastMsg := fmt.Sprintf("[%s]: %s", clientMsg.Name, clientMsg.Message)
+		if clientMsg.ID != "" {
+			// 附带 Stream ID，客户端断线重连后据此补齐错过的消息
+			broadcastMsg = tools.FormatStreamMessage(clientMsg.ID, broadcastMsg)
+		}
 
-		// 广播给所有客户端
-		for name, conn := range s.clients {
-			err := tools.SendMessage(conn, broadcastMsg)
-			if err != nil {
-				fmt.Printf("发送聊天消息给 %s 失败，标记清理: %v\n", name, err)
-				connsToCleanup = append(connsToCleanup, conn)
+		// 广播给所有客户端的所有会话
+		for name, conns := range s.clients {
+			for _, conn := range conns {
+				recipients++
+				err := deliverMessage(conn, broadcastMsg, clientMsg)
+				if err != nil {
+					slog.Warn("发送聊天消息失败，标记清理", "user", name, "msg_type", clientMsg.Type, "remote_addr", conn.RemoteAddr().String(), "error", err)
+					connsToCleanup = append(connsToCleanup, conn)
+				}
 			}
 		}
 
 	default:
 		// 忽略未知类型消息
+		s.mutex.RUnlock()
 		return
 	}
 
 	s.mutex.RUnlock() // 🌟 释放读锁
+	s.stats.ObserveFanout(time.Since(start), recipients)
 
 	// --- 连接清理逻辑 ---
 	// 遍历收集到的失效连接列表，将它们送入注销通道进行异步清理。
@@ -239,7 +299,7 @@ func (s *Server) broadcastMessage(clientMsg *ClientMessage) {
 	}
 }
 func (s *Server) Stop() {
-	fmt.Println("正在关闭服务器...")
+	slog.Info("正在关闭服务器")
 	select {
 	case <-s.Done:
 		// done 通道已关闭，不需要重复操作
@@ -247,22 +307,30 @@ func (s *Server) Stop() {
 		// 关闭 done 通道，解除 main goroutine 的阻塞
 		close(s.Done)
 	}
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.httpServer.Shutdown(ctx)
+		cancel()
+	}
 	if s.userDB != nil {
 		s.userDB.Close()
 	}
 	if s.asyncQueue != nil && s.asyncQueue.Client != nil {
 		s.asyncQueue.Client.Close()
-		fmt.Println("Redis 异步队列连接已关闭。")
+		slog.Info("Redis 异步队列连接已关闭")
 	}
 	s.mutex.Lock()
-	for name, conn := range s.clients {
-		tools.SendMessage(conn, "系统: 服务器正在关闭，连接即将断开")
-		conn.Close()
-		fmt.Printf("已断开: %s\n", name)
+	for name, conns := range s.clients {
+		for _, conn := range conns {
+			tools.SendMessage(conn, "系统: 服务器正在关闭，连接即将断开")
+			conn.Close()
+			slog.Info("已断开连接", "user", name, "remote_addr", conn.RemoteAddr().String())
+		}
 	}
-	s.clients = make(map[string]net.Conn)
+	s.clients = make(map[string][]net.Conn)
 	s.clientConnToName = make(map[net.Conn]string)
+	s.connectedAt = make(map[net.Conn]time.Time)
 	s.mutex.Unlock()
 
-	fmt.Println("服务器已关闭")
+	slog.Info("服务器已关闭")
 }
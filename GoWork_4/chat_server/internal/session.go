@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/db"
+	"GoWork_4/tools"
+	"fmt"
+	"log/slog"
+	"net"
+)
+
+// issueSession 为登录成功的连接签发会话令牌并下发给客户端
+// Redis 不可用时不签发，客户端断线后只能重新输入密码登录
+func (s *Server) issueSession(conn net.Conn, name string) {
+	if s.sessionSigner == nil || s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		return
+	}
+	token, claims, err := s.sessionSigner.Issue(name, s.sessionTTL)
+	if err != nil {
+		slog.Error("签发会话令牌失败", "user", name, "error", err)
+		return
+	}
+	if err := s.asyncQueue.SaveSession(claims.ID, name, s.sessionTTL); err != nil {
+		slog.Error("保存会话令牌失败", "user", name, "error", err)
+		return
+	}
+
+	s.mutex.Lock()
+	s.sessions[conn] = claims
+	s.mutex.Unlock()
+
+	if err := tools.SendMessage(conn, tools.FormatSessionToken(token)); err != nil {
+		slog.Warn("下发会话令牌失败", "user", name, "remote_addr", conn.RemoteAddr().String(), "error", err)
+	}
+}
+
+// handleResume 使用会话令牌恢复登录，跳过昵称和密码输入
+// 参数 token 是客户端在 /resume 命令中提交的令牌
+// 返回值与 handleLogin 一致：true 表示连接已进入聊天或已断开，false 表示回到主菜单
+func (s *Server) handleResume(conn net.Conn, token string) bool {
+	if s.sessionSigner == nil || s.asyncQueue == nil || s.asyncQueue.Client == nil {
+		return !s.rejectResume(conn, "", "会话功能不可用")
+	}
+	claims, err := s.sessionSigner.Verify(token)
+	if err != nil {
+		return !s.rejectResume(conn, "", err.Error())
+	}
+	user, found, err := s.asyncQueue.LookupSession(claims.ID)
+	if err != nil {
+		slog.Error("查询会话令牌失败", "user", claims.User, "remote_addr", conn.RemoteAddr().String(), "error", err)
+		return !s.rejectResume(conn, claims.User, "会话存储不可用")
+	}
+	if !found || user != claims.User {
+		return !s.rejectResume(conn, claims.User, "令牌已失效")
+	}
+	if remaining, locked := s.loginLockout(conn, claims.User); locked {
+		// 锁定期内令牌同样不能使用，否则被锁定的账号可以凭旧令牌绕过锁定
+		return !s.rejectResume(conn, claims.User, fmt.Sprintf("账号或IP已被临时锁定，请 %s 后再试", formatLockout(remaining)))
+	}
+	if s.isBotName(claims.User) {
+		return !s.rejectResume(conn, claims.User, "昵称被机器人占用")
+	}
+	if s.rejectsDuplicateResume(claims.User) {
+		return !s.rejectResume(conn, claims.User, "昵称已在线")
+	}
+
+	s.recordAuthEvent(conn, db.AuthEventResume, claims.User, db.AuthOutcomeSuccess, "")
+	first := s.registerClient(conn, claims.User)
+	s.mutex.Lock()
+	s.sessions[conn] = claims
+	s.mutex.Unlock()
+	slog.Info("会话已恢复", "user", claims.User, "remote_addr", conn.RemoteAddr().String())
+
+	if err := tools.SendMessage(conn, fmt.Sprintf("欢迎回来 %s！会话已恢复，开始聊天吧...\n使用 /help 查看可用命令", claims.User)); err != nil {
+		return false
+	}
+	if first {
+		s.announceJoin(conn, claims.User)
+	}
+	s.handleClientChat(conn, claims.User)
+	return true
+}
+
+// rejectResume 记录失败的会话恢复并通知客户端
+// 返回值表示通知是否发送成功
+func (s *Server) rejectResume(conn net.Conn, user, reason string) bool {
+	slog.Warn("会话恢复失败", "user", user, "remote_addr", conn.RemoteAddr().String(), "reason", reason)
+	s.recordAuthEvent(conn, db.AuthEventResume, user, db.AuthOutcomeFailure, reason)
+	return tools.SendMessage(conn, fmt.Sprintf("会话恢复失败：%s，请重新登录。", reason)) == nil
+}
+
+// revokeSession 吊销连接当前使用的会话令牌
+// 返回值表示是否存在需要吊销的令牌
+func (s *Server) revokeSession(conn net.Conn) (bool, error) {
+	s.mutex.Lock()
+	claims, ok := s.sessions[conn]
+	delete(s.sessions, conn)
+	s.mutex.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, s.asyncQueue.RevokeSession(claims.ID, claims.User)
+}
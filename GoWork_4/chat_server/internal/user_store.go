@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"GoWork_4/chat_server/auth"
+	"GoWork_4/chat_server/config"
+	"GoWork_4/chat_server/db"
+	"fmt"
+	"log/slog"
+)
+
+// buildUserStore 根据配置 CHAT_AUTH_BACKEND 创建账号存储
+// 参数 udb 是已连接的 MySQL（可为 nil），mysql 后端直接使用它
+// 后端名称无法识别或账号文件加载失败时返回错误，不会悄悄改用其他后端
+func buildUserStore(cfg *config.Config, udb *db.UserDB) (auth.UserStore, error) {
+	switch cfg.AuthBackend {
+	case auth.BackendMySQL:
+		return auth.NewMySQLStore(udb), nil
+	case auth.BackendFile:
+		store, err := auth.NewFileStore(cfg.AuthFile, cfg.BcryptCost)
+		if err != nil {
+			return nil, fmt.Errorf("文件账号存储不可用：%w", err)
+		}
+		slog.Info("使用文件账号存储", "path", store.Path())
+		return store, nil
+	case auth.BackendMemory:
+		slog.Warn("使用内存账号存储，服务器重启后注册的账号将全部丢失")
+		return auth.NewMemoryStore(cfg.BcryptCost), nil
+	default:
+		return nil, fmt.Errorf("未知的账号存储后端 '%s'，可选值：%s、%s、%s", cfg.AuthBackend, auth.BackendMySQL, auth.BackendFile, auth.BackendMemory)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"GoWork_4/chat_server/config"
+	"GoWork_4/chat_server/db"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = "用法: chat-server migrate up|down [n]|status"
+
+// runMigrate 处理 migrate 子命令，返回进程退出码
+// up 应用所有未应用的迁移，down [n] 回滚最近 n 个迁移（默认 1），status 列出迁移状态
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	udb := db.ConnectDB(cfg.MySQLDSN())
+	if udb == nil {
+		fmt.Fprintln(os.Stderr, "无法连接数据库")
+		return 1
+	}
+	defer udb.Close()
+
+	switch args[0] {
+	case "up":
+		done, err := udb.MigrateUp()
+		for _, m := range done {
+			fmt.Printf("已应用 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+			steps = n
+		}
+		done, err := udb.MigrateDown(steps)
+		for _, m := range done {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		states, err := udb.MigrationStatus()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, st := range states {
+			if st.Applied {
+				fmt.Printf("[已应用] %04d_%s（%s）\n", st.Version, st.Name, st.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("[未应用] %04d_%s\n", st.Version, st.Name)
+			}
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -1,19 +1,33 @@
 package main
 
 import (
+	"GoWork_4/chat_server/config"
 	"GoWork_4/chat_server/internal"
-	"fmt"
+	"log/slog"
+	"os"
 )
 
 // main 主程序入口，创建服务器实例并启动监听，同时提供手动关闭机制
+// 以 "migrate" 作为第一个参数运行时只执行数据库迁移，不启动服务器
 func main() {
-	server := internal.NewServer()
+	cfg := config.Load()
+	slog.SetDefault(newLogger(cfg))
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+
+	server, err := internal.NewServer(cfg)
+	if err != nil {
+		slog.Error("服务器初始化失败", "error", err)
+		os.Exit(1)
+	}
 
 	go server.Start("15000")
-	fmt.Println("服务器已启动，等待外部信号关闭...")
+	slog.Info("服务器已启动，等待外部信号关闭", "port", "15000")
 
 	// 阻塞主 goroutine，直到服务器的 done 通道被关闭
 	<-server.Done
 
-	fmt.Println("服务器已关闭。")
+	slog.Info("服务器已关闭")
 }
@@ -0,0 +1,122 @@
+// Package nickname 提供昵称的 Unicode 归一化、合法性校验和易混淆检测
+package nickname
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength 昵称最多允许的字符数（按 Unicode 字符计）
+const MaxLength = 20
+
+// forbiddenChars 昵称中不允许出现的特殊字符
+const forbiddenChars = "\\/:*?\"<>|"
+
+// Normalize 将昵称去除首尾空白并转换为 NFC 形式
+// 同一个字符的组合形式和预组合形式（如 "é" 的两种写法）归一化后相同
+func Normalize(name string) string {
+	return norm.NFC.String(strings.TrimSpace(name))
+}
+
+// Validate 验证昵称是否合法，name 应为 Normalize 之后的结果
+// 允许 Unicode 字母、数字、组合附加符号，以及除 forbiddenChars 以外的可打印 ASCII 字符
+// 返回值 valid 表示验证结果，reason 提供错误原因描述
+func Validate(name string) (bool, string) {
+	if name == "" {
+		return false, "昵称不能为空"
+	}
+	if !utf8.ValidString(name) {
+		return false, "昵称包含非法字符"
+	}
+	if utf8.RuneCountInString(name) > MaxLength {
+		return false, "昵称长度不能超过20个字符"
+	}
+
+	for i, char := range name {
+		if strings.ContainsRune(forbiddenChars, char) {
+			return false, "昵称包含不允许的特殊字符"
+		}
+		switch {
+		case unicode.IsLetter(char), unicode.IsDigit(char):
+		case unicode.IsMark(char):
+			if i == 0 {
+				return false, "昵称不能以组合符号开头"
+			}
+		case char >= 32 && char <= 126:
+			// 兼容旧版规则允许的 ASCII 符号
+		default:
+			return false, "昵称包含非法字符"
+		}
+	}
+
+	return true, ""
+}
+
+// Skeleton 返回昵称的骨架，骨架相同的两个昵称视为容易混淆
+// 计算方式：NFKD 分解并去掉组合符号，大小写折叠，再将形近字符映射为同一个代表字符
+func Skeleton(name string) string {
+	// cases.Caser 有内部状态，不能在协程间共享，因此每次调用时新建
+	decomposed := norm.NFKD.String(cases.Fold().String(norm.NFKC.String(name)))
+
+	var sb strings.Builder
+	sb.Grow(len(decomposed))
+	for _, char := range decomposed {
+		if unicode.IsMark(char) {
+			continue
+		}
+		if mapped, ok := confusables[char]; ok {
+			char = mapped
+		}
+		sb.WriteRune(char)
+	}
+	return sb.String()
+}
+
+// confusables 形近字符到代表字符的映射
+// 只收录与拉丁字母、数字外形几乎一致的常见字符，全角字符已由 NFKC 处理
+var confusables = map[rune]rune{
+	// 数字与字母
+	'0': 'o',
+	'1': 'l',
+	'i': 'l',
+	'|': 'l',
+	// 西里尔字母
+	'а': 'a',
+	'в': 'b',
+	'е': 'e',
+	'к': 'k',
+	'м': 'm',
+	'н': 'h',
+	'о': 'o',
+	'р': 'p',
+	'с': 'c',
+	'т': 't',
+	'у': 'y',
+	'х': 'x',
+	'і': 'l',
+	'ј': 'j',
+	'ѕ': 's',
+	'ԁ': 'd',
+	'ԛ': 'q',
+	'ԝ': 'w',
+	// 希腊字母
+	'α': 'a',
+	'β': 'b',
+	'ε': 'e',
+	'η': 'n',
+	'ι': 'l',
+	'κ': 'k',
+	'ν': 'v',
+	'ο': 'o',
+	'ρ': 'p',
+	'τ': 't',
+	'υ': 'u',
+	'χ': 'x',
+	// 其他
+	'ı': 'l',
+	'ℓ': 'l',
+}
@@ -0,0 +1,75 @@
+package nickname
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{"ASCII 字母数字", "bob123", true},
+		{"中文", "张三", true},
+		{"允许的 ASCII 符号", "bob_smith-1", true},
+		{"带附加符号的字母", "\u00e9mile", true},
+		{"组合附加符号", "e\u0301mile", true},
+		{"空昵称", "", false},
+		{"最大长度", strings.Repeat("张", MaxLength), true},
+		{"超过最大长度", strings.Repeat("张", MaxLength+1), false},
+		{"禁止的特殊字符", "bob/alice", false},
+		{"冒号", "bob:1", false},
+		{"以组合符号开头", "\u0301bob", false},
+		{"控制字符", "bob\x00", false},
+		{"非法 UTF-8", "bob\xff", false},
+		{"emoji", "bob😀", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, reason := Validate(tt.input)
+			if valid != tt.valid {
+				t.Fatalf("Validate(%q) = (%v, %q)，期望 %v", tt.input, valid, reason, tt.valid)
+			}
+			if !valid && reason == "" {
+				t.Fatal("校验失败时应给出原因")
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	// "é" 的组合形式和预组合形式归一化后相同
+	if got, want := Normalize("  e\u0301mile "), "\u00e9mile"; got != want {
+		t.Fatalf("Normalize 结果为 %q，期望 %q", got, want)
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		confusing bool
+	}{
+		{"相同", "bob", "bob", true},
+		{"仅大小写不同", "bob", "BoB", true},
+		{"数字 0 与字母 o", "bob", "b0b", true},
+		{"数字 1 与字母 l", "alice", "a1ice", true},
+		{"字母 i 与字母 l", "bill", "blll", true},
+		{"西里尔字母", "alice", "аlice", true},
+		{"去掉附加符号", "emile", "\u00e9mile", true},
+		{"全角字符", "bob", "ｂｏｂ", true},
+		{"不同的昵称", "bob", "alice", false},
+		{"中文", "张三", "张三", true},
+		{"不同的中文", "张三", "李四", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Skeleton(tt.a) == Skeleton(tt.b)
+			if got != tt.confusing {
+				t.Fatalf("Skeleton(%q)=%q，Skeleton(%q)=%q，期望易混淆：%v", tt.a, Skeleton(tt.a), tt.b, Skeleton(tt.b), tt.confusing)
+			}
+		})
+	}
+}
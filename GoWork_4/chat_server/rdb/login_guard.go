@@ -0,0 +1,210 @@
+package rdb
+
+import (
+	"GoWork_4/chat_server/nickname"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// LoginFailUserKeyPrefix 账号登录失败计数键前缀，完整键为 chat_login_fail:user:<昵称骨架>
+	LoginFailUserKeyPrefix = "chat_login_fail:user:"
+	// LoginFailIPKeyPrefix IP 登录失败计数键前缀，完整键为 chat_login_fail:ip:<IP>
+	LoginFailIPKeyPrefix = "chat_login_fail:ip:"
+	// LoginLockUserKeyPrefix 账号锁定键前缀，完整键为 chat_login_lock:user:<昵称骨架>，键存在即表示账号处于锁定期
+	LoginLockUserKeyPrefix = "chat_login_lock:user:"
+	// LoginLockIPKeyPrefix IP 锁定键前缀，键存在即表示该 IP 处于锁定期
+	LoginLockIPKeyPrefix = "chat_login_lock:ip:"
+)
+
+// loginFailUserKey 返回账号登录失败计数键
+// 按昵称骨架而不是输入的写法计数，攻击者轮换大小写或形近字符（bob、Bob、b0b）时仍累计到同一个计数上
+func loginFailUserKey(user string) string {
+	return LoginFailUserKeyPrefix + nickname.Skeleton(user)
+}
+
+// loginLockUserKey 返回账号锁定键，与 loginFailUserKey 一样按昵称骨架区分账号
+func loginLockUserKey(user string) string {
+	return LoginLockUserKeyPrefix + nickname.Skeleton(user)
+}
+
+// LoginGuardPolicy 登录防暴力破解策略
+// 失败计数按账号和 IP 分别保存在 Redis 中，跨连接、跨服务器实例共享
+type LoginGuardPolicy struct {
+	MaxUserFailures int64         // 账号在统计窗口内允许的最大失败次数，达到后锁定账号，0 表示不锁定
+	MaxIPFailures   int64         // IP 在统计窗口内允许的最大失败次数，达到后锁定 IP，0 表示不锁定
+	FailureWindow   time.Duration // 失败次数的统计窗口，最后一次失败后经过该时长计数清零
+	LockoutDuration time.Duration // 锁定时长
+	DelayStep       time.Duration // 渐进延迟的基数，第 n 次失败后等待 DelayStep*2^(n-1)
+	MaxDelay        time.Duration // 渐进延迟的上限
+}
+
+// DefaultLoginGuard 默认防暴力破解策略
+var DefaultLoginGuard = LoginGuardPolicy{
+	MaxUserFailures: 5,
+	MaxIPFailures:   20,
+	FailureWindow:   15 * time.Minute,
+	LockoutDuration: 15 * time.Minute,
+	DelayStep:       500 * time.Millisecond,
+	MaxDelay:        8 * time.Second,
+}
+
+// Sanitize 将非正数的统计窗口和锁定时长替换为默认值
+// Redis 中过期时间为 0 的 SET 永不过期、EXPIRE 会立即删除键，分别导致永久锁定和计数失效
+func (p LoginGuardPolicy) Sanitize() LoginGuardPolicy {
+	if p.FailureWindow <= 0 {
+		slog.Warn("登录失败统计窗口必须大于 0，使用默认值", "value", p.FailureWindow, "default", DefaultLoginGuard.FailureWindow)
+		p.FailureWindow = DefaultLoginGuard.FailureWindow
+	}
+	if p.LockoutDuration <= 0 {
+		slog.Warn("登录锁定时长必须大于 0，使用默认值", "value", p.LockoutDuration, "default", DefaultLoginGuard.LockoutDuration)
+		p.LockoutDuration = DefaultLoginGuard.LockoutDuration
+	}
+	return p
+}
+
+// Delay 返回第 failures 次失败后应等待的时长
+func (p LoginGuardPolicy) Delay(failures int64) time.Duration {
+	if failures <= 0 || p.DelayStep <= 0 {
+		return 0
+	}
+	delay := p.DelayStep
+	for i := int64(1); i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// LoginFailure 一次登录失败后的计数结果
+type LoginFailure struct {
+	UserFailures int64         // 账号在统计窗口内的失败次数
+	IPFailures   int64         // IP 在统计窗口内的失败次数
+	Locked       bool          // 本次失败是否触发了锁定
+	LockedFor    time.Duration // 触发锁定时的锁定时长
+}
+
+// LoginLockout 查询账号或 IP 是否处于锁定期
+// 返回值 remaining 为剩余锁定时长，账号和 IP 均被锁定时取较长者
+func (rqc *RedisQueueClient) LoginLockout(user, ip string) (remaining time.Duration, locked bool, err error) {
+	if rqc == nil || rqc.Client == nil {
+		return 0, false, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	for _, key := range []string{loginLockUserKey(user), LoginLockIPKeyPrefix + ip} {
+		ttl, err := rqc.Client.PTTL(ctx, key).Result()
+		if err != nil {
+			return 0, false, fmt.Errorf("查询登录锁定状态失败：%w", err)
+		}
+		// 键不存在时 PTTL 返回负值
+		if ttl > remaining {
+			remaining, locked = ttl, true
+		}
+	}
+	return remaining, locked, nil
+}
+
+// RecordLoginFailure 记录一次登录失败，达到阈值时锁定账号或 IP
+func (rqc *RedisQueueClient) RecordLoginFailure(user, ip string) (*LoginFailure, error) {
+	if rqc == nil || rqc.Client == nil {
+		return nil, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	p := rqc.LoginGuard.Sanitize()
+
+	var userIncr, ipIncr *redis.IntCmd
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		userIncr = pipe.Incr(ctx, loginFailUserKey(user))
+		pipe.Expire(ctx, loginFailUserKey(user), p.FailureWindow)
+		ipIncr = pipe.Incr(ctx, LoginFailIPKeyPrefix+ip)
+		pipe.Expire(ctx, LoginFailIPKeyPrefix+ip, p.FailureWindow)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("记录登录失败次数失败：%w", err)
+	}
+
+	result := &LoginFailure{UserFailures: userIncr.Val(), IPFailures: ipIncr.Val()}
+	lock := func(lockKey, failKey string) error {
+		_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, lockKey, time.Now().Unix(), p.LockoutDuration)
+			// 锁定后重新计数，解锁后再次失败需要重新累计
+			pipe.Del(ctx, failKey)
+			return nil
+		})
+		return err
+	}
+	if p.MaxUserFailures > 0 && result.UserFailures >= p.MaxUserFailures {
+		if err := lock(loginLockUserKey(user), loginFailUserKey(user)); err != nil {
+			return nil, fmt.Errorf("锁定账号失败：%w", err)
+		}
+		result.Locked = true
+	}
+	if p.MaxIPFailures > 0 && result.IPFailures >= p.MaxIPFailures {
+		if err := lock(LoginLockIPKeyPrefix+ip, LoginFailIPKeyPrefix+ip); err != nil {
+			return nil, fmt.Errorf("锁定IP失败：%w", err)
+		}
+		result.Locked = true
+	}
+	if result.Locked {
+		result.LockedFor = p.LockoutDuration
+	}
+	return result, nil
+}
+
+// ClearLoginFailures 登录成功后清空账号的失败计数
+// IP 计数不清空，避免攻击者用自己的账号登录来重置 IP 计数
+func (rqc *RedisQueueClient) ClearLoginFailures(user string) error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis队列客户端未初始化")
+	}
+	if err := rqc.Client.Del(context.Background(), loginFailUserKey(user)).Err(); err != nil {
+		return fmt.Errorf("清空登录失败次数失败：%w", err)
+	}
+	return nil
+}
+
+// UnlockUser 解除账号锁定并清空其失败计数
+// 只影响账号维度，用户所在 IP 的锁定需要通过 UnlockIP 单独解除
+// 返回值表示账号在解锁前是否处于锁定期
+func (rqc *RedisQueueClient) UnlockUser(user string) (bool, error) {
+	wasLocked, err := rqc.unlock(loginLockUserKey(user), loginFailUserKey(user))
+	if err != nil {
+		return false, fmt.Errorf("解除账号锁定失败：%w", err)
+	}
+	return wasLocked, nil
+}
+
+// UnlockIP 解除 IP 锁定并清空其失败计数
+// 返回值表示 IP 在解锁前是否处于锁定期
+func (rqc *RedisQueueClient) UnlockIP(ip string) (bool, error) {
+	wasLocked, err := rqc.unlock(LoginLockIPKeyPrefix+ip, LoginFailIPKeyPrefix+ip)
+	if err != nil {
+		return false, fmt.Errorf("解除IP锁定失败：%w", err)
+	}
+	return wasLocked, nil
+}
+
+// unlock 删除锁定键和失败计数键，返回值表示锁定键在删除前是否存在
+func (rqc *RedisQueueClient) unlock(lockKey, failKey string) (bool, error) {
+	if rqc == nil || rqc.Client == nil {
+		return false, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	var lockDel *redis.IntCmd
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		lockDel = pipe.Del(ctx, lockKey)
+		pipe.Del(ctx, failKey)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return lockDel.Val() > 0, nil
+}
@@ -0,0 +1,64 @@
+package rdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginGuardPolicyDelay(t *testing.T) {
+	policy := LoginGuardPolicy{DelayStep: 500 * time.Millisecond, MaxDelay: 8 * time.Second}
+	tests := []struct {
+		name     string
+		policy   LoginGuardPolicy
+		failures int64
+		want     time.Duration
+	}{
+		{"没有失败", policy, 0, 0},
+		{"负数次失败", policy, -1, 0},
+		{"第 1 次失败", policy, 1, 500 * time.Millisecond},
+		{"第 2 次失败", policy, 2, time.Second},
+		{"第 3 次失败", policy, 3, 2 * time.Second},
+		{"第 5 次失败达到上限", policy, 5, 8 * time.Second},
+		{"超过上限后保持不变", policy, 6, 8 * time.Second},
+		{"次数很大时不溢出", policy, 1000, 8 * time.Second},
+		{"上限不是基数的整数倍", LoginGuardPolicy{DelayStep: 3 * time.Second, MaxDelay: 10 * time.Second}, 3, 10 * time.Second},
+		{"不启用延迟", LoginGuardPolicy{MaxDelay: 8 * time.Second}, 3, 0},
+		{"没有上限", LoginGuardPolicy{DelayStep: time.Second}, 1, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.failures); got != tt.want {
+				t.Fatalf("Delay(%d) = %s，期望 %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginGuardPolicySanitize(t *testing.T) {
+	p := LoginGuardPolicy{FailureWindow: 0, LockoutDuration: -time.Minute, MaxUserFailures: 3}.Sanitize()
+	if p.FailureWindow != DefaultLoginGuard.FailureWindow || p.LockoutDuration != DefaultLoginGuard.LockoutDuration {
+		t.Fatalf("非正数的时长应替换为默认值，实际为 %+v", p)
+	}
+	if p.MaxUserFailures != 3 {
+		t.Fatalf("Sanitize 不应修改其他字段，实际为 %+v", p)
+	}
+	custom := LoginGuardPolicy{FailureWindow: time.Minute, LockoutDuration: time.Hour}
+	if got := custom.Sanitize(); got != custom {
+		t.Fatalf("合法的策略不应被修改，实际为 %+v", got)
+	}
+}
+
+func TestLoginGuardUserKeys(t *testing.T) {
+	// 大小写或形近字符不同的写法计入同一账号
+	for _, variant := range []string{"Bob", "BOB", "b0b"} {
+		if loginFailUserKey(variant) != loginFailUserKey("bob") {
+			t.Errorf("%q 与 bob 的失败计数键不同", variant)
+		}
+		if loginLockUserKey(variant) != loginLockUserKey("bob") {
+			t.Errorf("%q 与 bob 的锁定键不同", variant)
+		}
+	}
+	if loginFailUserKey("alice") == loginFailUserKey("bob") {
+		t.Error("不同账号的失败计数键相同")
+	}
+}
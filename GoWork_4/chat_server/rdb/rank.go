@@ -0,0 +1,174 @@
+package rdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RankWindow 活跃度排行榜的时间窗口
+type RankWindow string
+
+const (
+	RankDay   RankWindow = "day"   // 今日排行
+	RankWeek  RankWindow = "week"  // 本周排行（ISO 周）
+	RankMonth RankWindow = "month" // 本月排行
+	RankAll   RankWindow = "all"   // 总排行，即 ChatRankKey
+)
+
+// RankWindows 所有排行榜窗口，按时间跨度从小到大排列
+var RankWindows = []RankWindow{RankDay, RankWeek, RankMonth, RankAll}
+
+// ParseRankWindow 将字符串解析为排行榜窗口
+func ParseRankWindow(s string) (RankWindow, bool) {
+	for _, w := range RankWindows {
+		if string(w) == s {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// Label 返回窗口的中文名称
+func (w RankWindow) Label() string {
+	switch w {
+	case RankDay:
+		return "今日"
+	case RankWeek:
+		return "本周"
+	case RankMonth:
+		return "本月"
+	default:
+		return "总"
+	}
+}
+
+// key 返回窗口在时间 t 对应的有序集合键名
+// 例如 chat_activity_rank:day:20261018、chat_activity_rank:week:2026-W42
+func (w RankWindow) key(t time.Time) string {
+	switch w {
+	case RankDay:
+		return ChatRankKey + ":day:" + t.Format("20060102")
+	case RankWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%s:week:%d-W%02d", ChatRankKey, year, week)
+	case RankMonth:
+		return ChatRankKey + ":month:" + t.Format("200601")
+	default:
+		return ChatRankKey
+	}
+}
+
+// ttl 返回窗口键的过期时间，留出余量以便窗口结束后仍可查看，0 表示永不过期
+func (w RankWindow) ttl() time.Duration {
+	switch w {
+	case RankDay:
+		return 48 * time.Hour
+	case RankWeek:
+		return 15 * 24 * time.Hour
+	case RankMonth:
+		return 62 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// RankEntry 排行榜中的一条记录
+type RankEntry struct {
+	Rank     int64  `json:"rank"`     // 名次，从 1 开始
+	Username string `json:"username"` // 用户名
+	Score    int64  `json:"score"`    // 消息数
+}
+
+// incrRankWindows 在所有窗口的排行榜中为用户加一，并刷新窗口键的过期时间
+func (rqc *RedisQueueClient) incrRankWindows(ctx context.Context, username string) error {
+	now := time.Now()
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, w := range RankWindows {
+			key := w.key(now)
+			pipe.ZIncrBy(ctx, key, 1, username)
+			if ttl := w.ttl(); ttl > 0 {
+				pipe.Expire(ctx, key, ttl)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// GetRankWindow 获取指定窗口的活跃度排行
+// 参数:
+//   - window: 排行榜窗口
+//   - count: 需要获取的排名数量
+func (rqc *RedisQueueClient) GetRankWindow(window RankWindow, count int64) ([]RankEntry, error) {
+	if rqc == nil || rqc.Client == nil {
+		return nil, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+
+	results, err := rqc.Client.ZRevRangeWithScores(ctx, window.key(time.Now()), 0, count-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取%s活跃度排名失败：%w", window.Label(), err)
+	}
+	entries := make([]RankEntry, 0, len(results))
+	for i, z := range results {
+		entries = append(entries, RankEntry{
+			Rank:     int64(i + 1),
+			Username: fmt.Sprint(z.Member),
+			Score:    int64(z.Score),
+		})
+	}
+	return entries, nil
+}
+
+// GetUserRank 获取用户在指定窗口中的名次
+// 返回值 entry 为用户的排名信息，found 为 false 表示用户在该窗口内没有活跃记录
+func (rqc *RedisQueueClient) GetUserRank(window RankWindow, username string) (entry RankEntry, found bool, err error) {
+	if rqc == nil || rqc.Client == nil {
+		return RankEntry{}, false, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	key := window.key(time.Now())
+
+	rank, err := rqc.Client.ZRevRank(ctx, key, username).Result()
+	if err == redis.Nil {
+		return RankEntry{}, false, nil
+	}
+	if err != nil {
+		return RankEntry{}, false, fmt.Errorf("获取用户排名失败：%w", err)
+	}
+	score, err := rqc.Client.ZScore(ctx, key, username).Result()
+	if err != nil && err != redis.Nil {
+		return RankEntry{}, false, fmt.Errorf("获取用户活跃度失败：%w", err)
+	}
+	return RankEntry{Rank: rank + 1, Username: username, Score: int64(score)}, true, nil
+}
+
+// RemoveUserRank 从所有排行榜中移除用户，包括已结束但尚未过期的历史窗口
+func (rqc *RedisQueueClient) RemoveUserRank(username string) error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+
+	keys := []string{ChatRankKey}
+	iter := rqc.Client.Scan(ctx, 0, ChatRankKey+":*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("查找排行榜失败：%w", err)
+	}
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZRem(ctx, key, username)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("移除用户排名失败：%w", err)
+	}
+	return nil
+}
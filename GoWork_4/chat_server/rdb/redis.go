@@ -1,10 +1,13 @@
 package rdb
 
 import (
+	"GoWork_4/tools"
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
-	"log"
+	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,12 +15,24 @@ import (
 // RedisQueueClient 是一个基于 Redis 的队列客户端结构体，
 // 提供消息入队和消费功能。
 type RedisQueueClient struct {
-	Client    *redis.Client // Redis 客户端实例
-	QueueKey  string        // 队列在 Redis 中对应的键名
-	StreamKey string        // Stream 键名，用于存储日志消息
-	GroupKey  string        // 消费者组键名
+	Client     *redis.Client    // Redis 客户端实例
+	QueueKey   string           // 队列在 Redis 中对应的键名
+	StreamKey  string           // Stream 键名，用于存储日志消息
+	GroupKey   string           // 消费者组键名
+	Retention  RetentionPolicy  // 聊天历史保留策略
+	LoginGuard LoginGuardPolicy // 登录防暴力破解策略
 }
 
+// RetentionPolicy 聊天历史保留策略
+type RetentionPolicy struct {
+	MaxEntries    int64         // Stream 最多保留的条数，0 表示不限
+	MaxAge        time.Duration // 最长保留时间，按 MINID 裁剪，0 表示不限
+	KeepOnRestart bool          // 重启时是否保留聊天历史和活跃度排名
+}
+
+// DefaultRetention 默认保留策略：保留最近 1000 条，重启时保留数据
+var DefaultRetention = RetentionPolicy{MaxEntries: 1000, KeepOnRestart: true}
+
 const (
 	// ChatStreamKey 聊天历史记录流的键名，用于存储所有聊天消息的历史记录
 	ChatStreamKey = "chat_history_stream"
@@ -32,12 +47,36 @@ const (
 	ChatRankKey = "chat_activity_rank"
 	//ChatGroupKey 聊天消息的消费者组键名
 	ChatGroupKey = "chat_consumer_group"
+	// ArchiveGroupKey 聊天归档的消费者组键名，独立于广播消费者组，保证每条消息都会被归档
+	ArchiveGroupKey = "chat_archive_group"
 )
 
 type ChatMessage struct {
-	Name    string // 发送者昵称
-	Message string // 消息内容
-	Type    string // 消息类型 ("chat" 或 "system")
+	Name    string    // 发送者昵称
+	Message string    // 消息内容
+	Type    string    // 消息类型 ("chat" 或 "system")
+	ID      string    // Stream 消息 ID（仅消费时填充）
+	SentAt  time.Time // 消息入队时间，由 Stream 消息 ID 推算（仅消费时填充）
+}
+
+// parseChatMessage 将 Stream 中的消息解析为 ChatMessage
+func parseChatMessage(message redis.XMessage) *ChatMessage {
+	chatMsg := &ChatMessage{
+		Name:    fmt.Sprint(message.Values["sender"]),
+		Message: fmt.Sprint(message.Values["context"]),
+		Type:    fmt.Sprint(message.Values["type"]),
+		ID:      message.ID,
+	}
+	// Stream ID 形如 "<毫秒时间戳>-<序号>"
+	if ms, err := strconv.ParseInt(strings.SplitN(message.ID, "-", 2)[0], 10, 64); err == nil {
+		chatMsg.SentAt = time.UnixMilli(ms)
+	}
+	return chatMsg
+}
+
+// isBusyGroupErr 判断错误是否为消费者组已存在
+func isBusyGroupErr(err error) bool {
+	return strings.HasPrefix(err.Error(), "BUSYGROUP")
 }
 
 // NewRedisQueueClient 创建一个新的 Redis 队列客户端实例。
@@ -61,14 +100,16 @@ func NewRedisQueueClient(addr, password string, db int) *RedisQueueClient {
 
 	_, err := rdb.Ping(ctx).Result()
 	if err != nil {
-		log.Printf("连接 Redis 失败（异步队列功能禁用）: %v", err)
+		slog.Error("连接 Redis 失败，异步队列功能禁用", "addr", addr, "error", err)
 		return nil // 连接失败，返回 nil
 	}
-	fmt.Println("Redis 连接成功")
+	slog.Info("Redis 连接成功", "addr", addr, "db", db)
 	return &RedisQueueClient{
-		Client:    rdb,
-		StreamKey: TaskStreamKey,
-		GroupKey:  ChatGroupKey,
+		Client:     rdb,
+		StreamKey:  TaskStreamKey,
+		GroupKey:   ChatGroupKey,
+		Retention:  DefaultRetention,
+		LoginGuard: DefaultLoginGuard,
 	}
 }
 
@@ -80,7 +121,7 @@ func (rqc *RedisQueueClient) AsyncProduceMessage(msg *ChatMessage) error {
 
 	err := rqc.Client.XAdd(ctx, &redis.XAddArgs{
 		Stream: ChatStreamKey,
-		MaxLen: 1000,
+		MaxLen: rqc.Retention.MaxEntries,
 		Values: map[string]interface{}{
 			"sender":    msg.Name,
 			"context":   msg.Message,
@@ -100,7 +141,7 @@ func (rqc *RedisQueueClient) CreateChatConsumerGroup() error {
 	}
 	ctx := context.Background()
 	err := rqc.Client.XGroupCreateMkStream(ctx, ChatStreamKey, ChatGroupKey, "0").Err()
-	if err != nil && !strings.Contains(err.Error(), "BUSY GROUP") {
+	if err != nil && !isBusyGroupErr(err) {
 		return fmt.Errorf("创建 Redis Stream 消费者组失败: %v", err)
 	}
 	return nil
@@ -120,24 +161,19 @@ func (rqc *RedisQueueClient) StartChatConsumer(consumerName string, handler func
 			}).Result()
 			if err != nil {
 				if err != redis.Nil {
-					log.Printf("消费者%s读取stream失败：%v", consumerName, err)
+					slog.Error("读取 Stream 失败", "consumer", consumerName, "group", ChatGroupKey, "error", err)
 				}
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
 			for _, stream := range streams {
 				for _, message := range stream.Messages {
-					values := message.Values
-					chatMsg := &ChatMessage{
-						Name:    values["sender"].(string),
-						Message: values["context"].(string),
-						Type:    values["type"].(string),
-					}
+					chatMsg := parseChatMessage(message)
 					if chatMsg.Type != "system" {
 						handler(chatMsg)
 					}
 					if err = rqc.Client.XAck(ctx, ChatStreamKey, ChatGroupKey, message.ID).Err(); err != nil {
-						log.Printf("消费者 %s ACK 消息 %s 失败: %v", consumerName, message.ID, err)
+						slog.Error("ACK 消息失败", "consumer", consumerName, "group", ChatGroupKey, "stream_id", message.ID, "error", err)
 					}
 				}
 			}
@@ -145,6 +181,215 @@ func (rqc *RedisQueueClient) StartChatConsumer(consumerName string, handler func
 	}()
 }
 
+// ResetChatData 删除聊天历史 Stream 和活跃度排名，用于不保留历史的启动模式
+func (rqc *RedisQueueClient) ResetChatData() error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis 队列客户端未初始化")
+	}
+	ctx := context.Background()
+	keys := []string{ChatStreamKey}
+	for _, w := range RankWindows {
+		keys = append(keys, w.key(time.Now()))
+	}
+	if err := rqc.Client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("清空聊天历史失败: %v", err)
+	}
+	return nil
+}
+
+// EnforceRetention 按保留策略裁剪聊天历史 Stream
+// 返回值为本次删除的消息条数
+func (rqc *RedisQueueClient) EnforceRetention() (int64, error) {
+	if rqc == nil || rqc.Client == nil {
+		return 0, fmt.Errorf("redis 队列客户端未初始化")
+	}
+	ctx := context.Background()
+
+	var trimmed int64
+	if rqc.Retention.MaxAge > 0 {
+		// 早于 MINID 的消息会被删除，Stream ID 的前半部分即毫秒时间戳
+		minID := fmt.Sprintf("%d-0", time.Now().Add(-rqc.Retention.MaxAge).UnixMilli())
+		n, err := rqc.Client.XTrimMinID(ctx, ChatStreamKey, minID).Result()
+		if err != nil {
+			return trimmed, fmt.Errorf("按时间裁剪聊天历史失败: %v", err)
+		}
+		trimmed += n
+	}
+	if rqc.Retention.MaxEntries > 0 {
+		n, err := rqc.Client.XTrimMaxLen(ctx, ChatStreamKey, rqc.Retention.MaxEntries).Result()
+		if err != nil {
+			return trimmed, fmt.Errorf("按条数裁剪聊天历史失败: %v", err)
+		}
+		trimmed += n
+	}
+	return trimmed, nil
+}
+
+// ChatHistoryLen 返回聊天历史 Stream 当前保存的消息条数
+func (rqc *RedisQueueClient) ChatHistoryLen() (int64, error) {
+	if rqc == nil || rqc.Client == nil {
+		return 0, fmt.Errorf("redis 队列客户端未初始化")
+	}
+	return rqc.Client.XLen(context.Background(), ChatStreamKey).Result()
+}
+
+// String 返回保留策略的可读描述
+func (p RetentionPolicy) String() string {
+	maxEntries, maxAge, restart := "不限", "不限", "清空"
+	if p.MaxEntries > 0 {
+		maxEntries = fmt.Sprintf("%d 条", p.MaxEntries)
+	}
+	if p.MaxAge > 0 {
+		maxAge = p.MaxAge.String()
+	}
+	if p.KeepOnRestart {
+		restart = "保留"
+	}
+	return fmt.Sprintf("最多保留: %s，最长保留: %s，重启时: %s", maxEntries, maxAge, restart)
+}
+
+// StartRetentionJanitor 启动后台清理协程，按固定间隔执行保留策略
+// 参数:
+//   - interval: 执行间隔
+//   - done: 关闭时停止清理协程
+func (rqc *RedisQueueClient) StartRetentionJanitor(interval time.Duration, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				trimmed, err := rqc.EnforceRetention()
+				if err != nil {
+					slog.Error("历史清理失败", "error", err)
+					continue
+				}
+				if trimmed > 0 {
+					slog.Info("历史清理完成", "trimmed", trimmed)
+				}
+			}
+		}
+	}()
+}
+
+// ConsumerGroupInfo 聊天历史 Stream 上某个消费者组的状态
+type ConsumerGroupInfo struct {
+	Name    string // 消费者组名称
+	Pending int64  // 已投递但尚未确认的消息数
+	Lag     int64  // 尚未投递给该组的消息数，Redis 7 以下版本不支持时为 -1
+}
+
+// GetConsumerGroupInfo 通过 XINFO GROUPS 查询指定消费者组的积压情况
+func (rqc *RedisQueueClient) GetConsumerGroupInfo(group string) (*ConsumerGroupInfo, error) {
+	if rqc == nil || rqc.Client == nil {
+		return nil, fmt.Errorf("redis 队列客户端未初始化")
+	}
+	ctx := context.Background()
+
+	// go-redis v8 的 XInfoGroups 不包含 lag 字段，这里直接解析原始返回值
+	res, err := rqc.Client.Do(ctx, "XINFO", "GROUPS", ChatStreamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("查询消费者组信息失败: %v", err)
+	}
+	groups, _ := res.([]interface{})
+	for _, g := range groups {
+		fields, ok := g.([]interface{})
+		if !ok {
+			continue
+		}
+		info := &ConsumerGroupInfo{Lag: -1}
+		for i := 0; i+1 < len(fields); i += 2 {
+			switch fmt.Sprint(fields[i]) {
+			case "name":
+				info.Name = fmt.Sprint(fields[i+1])
+			case "pending":
+				info.Pending, _ = fields[i+1].(int64)
+			case "lag":
+				if lag, ok := fields[i+1].(int64); ok {
+					info.Lag = lag
+				}
+			}
+		}
+		if info.Name == group {
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("消费者组 %s 不存在", group)
+}
+
+// CreateArchiveConsumerGroup 创建聊天归档消费者组（如已存在则忽略）
+func (rqc *RedisQueueClient) CreateArchiveConsumerGroup() error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis 队列客户端未初始化")
+	}
+	ctx := context.Background()
+	err := rqc.Client.XGroupCreateMkStream(ctx, ChatStreamKey, ArchiveGroupKey, "0").Err()
+	if err != nil && !isBusyGroupErr(err) {
+		return fmt.Errorf("创建 Redis Stream 归档消费者组失败: %v", err)
+	}
+	return nil
+}
+
+// StartArchiveConsumer 启动聊天归档消费者
+// 启动时先处理本消费者尚未确认的消息，再读取新消息；只有 handler 成功后才确认消息，
+// 失败的消息保留在待确认列表中稍后重试，配合归档表的唯一键实现不丢不重。
+// 参数:
+//   - consumerName: 消费者名称
+//   - handler: 归档处理函数，返回错误表示归档失败
+func (rqc *RedisQueueClient) StartArchiveConsumer(consumerName string, handler func(msg *ChatMessage) error) {
+	ctx := context.Background()
+
+	go func() {
+		lastID := "0"
+		for {
+			streams, err := rqc.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    ArchiveGroupKey,
+				Consumer: consumerName,
+				Streams:  []string{ChatStreamKey, lastID},
+				Count:    100,
+				Block:    time.Second,
+			}).Result()
+			if err != nil {
+				if err != redis.Nil {
+					slog.Error("读取 Stream 失败", "consumer", consumerName, "group", ArchiveGroupKey, "error", err)
+				}
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+
+			received, failed := 0, false
+			for _, stream := range streams {
+				for _, message := range stream.Messages {
+					received++
+					chatMsg := parseChatMessage(message)
+					if chatMsg.Type != "system" {
+						if err := handler(chatMsg); err != nil {
+							slog.Error("归档消息失败", "consumer", consumerName, "group", ArchiveGroupKey, "stream_id", message.ID, "error", err)
+							failed = true
+							break
+						}
+					}
+					if err = rqc.Client.XAck(ctx, ChatStreamKey, ArchiveGroupKey, message.ID).Err(); err != nil {
+						slog.Error("ACK 消息失败", "consumer", consumerName, "group", ArchiveGroupKey, "stream_id", message.ID, "error", err)
+					}
+				}
+			}
+
+			switch {
+			case failed:
+				// 归档失败，稍后从待确认列表重新处理
+				lastID = "0"
+				time.Sleep(2 * time.Second)
+			case lastID == "0" && received == 0:
+				// 待确认消息已处理完毕，开始读取新消息
+				lastID = ">"
+			}
+		}
+	}()
+}
+
 // GetChatHistory 获取聊天历史记录
 // 参数:
 //
@@ -179,8 +424,124 @@ func (rqc *RedisQueueClient) GetChatHistory(count int64) ([]string, error) {
 	return history, nil
 }
 
+// HistoryQuery 分页查询聊天历史的条件
+type HistoryQuery struct {
+	Count  int64     // 本页最多返回的记录数
+	Before string    // 游标：只返回 ID 小于该值的记录，为空表示从最新记录开始
+	After  string    // 游标：只返回 ID 大于该值的记录并按时间正序翻页，用于断线重连后补齐消息；设置后忽略 Before
+	User   string    // 只返回该用户发送的记录，为空表示不过滤
+	Since  time.Time // 只返回该时间之后的记录，零值表示不限
+}
+
+// HistoryEntry 一条聊天历史记录
+type HistoryEntry struct {
+	ID        string `json:"id"`        // Stream 消息 ID，可作为分页游标
+	Sender    string `json:"sender"`    // 发送者昵称
+	Content   string `json:"content"`   // 消息内容
+	Timestamp string `json:"timestamp"` // 发送时间
+}
+
+// String 将历史记录格式化为 "[时间] 发送者: 内容"
+func (e HistoryEntry) String() string {
+	return fmt.Sprintf("[%s] %s: %s", e.Timestamp, e.Sender, e.Content)
+}
+
+// HistoryPage 一页聊天历史记录
+type HistoryPage struct {
+	Entries    []HistoryEntry // 按时间顺序排列的记录
+	NextCursor string         // 下一页的游标，为空表示没有更多记录；使用 After 查询时指向更新的记录
+}
+
+// GetChatHistoryPage 使用 XREVRANGE 游标从新到旧分页读取聊天历史
+// 参数 q 指定页大小、游标以及按用户和时间的过滤条件
+// 返回值为本页记录及下一页游标
+func (rqc *RedisQueueClient) GetChatHistoryPage(q HistoryQuery) (*HistoryPage, error) {
+	if rqc == nil || rqc.Client == nil {
+		return nil, fmt.Errorf("redis 队列客户端未初始化")
+	}
+	if q.After != "" {
+		return rqc.getChatHistoryAfter(q)
+	}
+
+	// "(" 前缀表示开区间，游标本身所在的记录不会重复返回
+	start := "+"
+	if q.Before != "" {
+		start = "(" + q.Before
+	}
+	stop := "-"
+	if !q.Since.IsZero() {
+		stop = fmt.Sprintf("%d-0", q.Since.UnixMilli())
+	}
+
+	page, err := rqc.scanChatHistory(q, start, stop, false)
+	if err != nil {
+		return nil, err
+	}
+	// XREVRANGE 结果从新到旧，翻转为时间顺序
+	slices.Reverse(page.Entries)
+	return page, nil
+}
+
+// getChatHistoryAfter 使用 XRANGE 从游标 q.After 之后按时间正序读取聊天历史
+// 返回值的 NextCursor 为本页最后一条记录的 ID，可继续作为 After 游标读取更新的记录
+func (rqc *RedisQueueClient) getChatHistoryAfter(q HistoryQuery) (*HistoryPage, error) {
+	start := "(" + q.After
+	if !q.Since.IsZero() {
+		if since := fmt.Sprintf("%d-0", q.Since.UnixMilli()); tools.CompareStreamIDs(since, q.After) > 0 {
+			start = since
+		}
+	}
+	return rqc.scanChatHistory(q, start, "+", true)
+}
+
+// scanChatHistory 从 start 开始分批扫描聊天 Stream，直到凑满 q.Count 条符合 q.User 的记录或扫描到 stop
+// 参数 forward 为 true 时使用 XRANGE 从旧到新扫描，否则使用 XREVRANGE 从新到旧扫描
+// 返回的记录保持扫描顺序，本页已满且后面可能还有记录时 NextCursor 为本页最后一条记录的 ID
+func (rqc *RedisQueueClient) scanChatHistory(q HistoryQuery, start, stop string, forward bool) (*HistoryPage, error) {
+	ctx := context.Background()
+	const scanBatch = 100
+
+	page := &HistoryPage{}
+	for int64(len(page.Entries)) < q.Count {
+		var messages []redis.XMessage
+		var err error
+		if forward {
+			messages, err = rqc.Client.XRangeN(ctx, ChatStreamKey, start, stop, scanBatch).Result()
+		} else {
+			messages, err = rqc.Client.XRevRangeN(ctx, ChatStreamKey, start, stop, scanBatch).Result()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取聊天历史失败:%v", err)
+		}
+		for i, message := range messages {
+			entry := HistoryEntry{
+				ID:        message.ID,
+				Sender:    fmt.Sprint(message.Values["sender"]),
+				Content:   fmt.Sprint(message.Values["context"]),
+				Timestamp: fmt.Sprint(message.Values["timestamp"]),
+			}
+			if q.User != "" && entry.Sender != q.User {
+				continue
+			}
+			page.Entries = append(page.Entries, entry)
+			if int64(len(page.Entries)) == q.Count {
+				// 本页已满，只有在后面可能还有记录时才返回游标
+				if i < len(messages)-1 || len(messages) == scanBatch {
+					page.NextCursor = message.ID
+				}
+				break
+			}
+		}
+		if int64(len(page.Entries)) == q.Count || len(messages) < scanBatch {
+			break
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+	return page, nil
+}
+
 // IncrUserAction 增加用户活跃度计数
-// 该函数通过将指定用户名在Redis有序集合中的分数加1来记录用户活跃度
+// 该函数将指定用户名在总榜以及今日、本周、本月排行榜中的分数加1来记录用户活跃度
 // 参数:
 //   - username: 需要增加活跃度的用户名
 //
@@ -192,8 +553,8 @@ func (rqc *RedisQueueClient) IncrUserAction(username string) error {
 	}
 	ctx := context.Background()
 
-	// 使用ZIncrBy命令将用户名对应的分数加1，实现活跃度计数
-	err := rqc.Client.ZIncrBy(ctx, ChatRankKey, 1, username).Err()
+	// 使用ZIncrBy命令将各窗口中用户名对应的分数加1，实现活跃度计数
+	err := rqc.incrRankWindows(ctx, username)
 	if err != nil {
 		return fmt.Errorf("增加用户活跃度失败：%v", err)
 	}
@@ -212,21 +573,16 @@ func (rqc *RedisQueueClient) GetActivityRank(count int64) ([]string, error) {
 	if rqc == nil || rqc.Client == nil {
 		return nil, fmt.Errorf("redis队列客户端未初始化")
 	}
-	ctx := context.Background()
 
-	// 使用ZRevRangeWithScores命令获取按分数降序排列的用户排名数据
-	results, err := rqc.Client.ZRevRangeWithScores(ctx, ChatRankKey, 0, count-1).Result()
+	entries, err := rqc.GetRankWindow(RankAll, count)
 	if err != nil {
-		return nil, fmt.Errorf("获取活跃度排名失败：%w", err)
+		return nil, err
 	}
 
 	// 格式化排名结果为可读字符串
 	var rankList []string
-	for i, z := range results {
-		rank := i + 1
-		username := z.Member.(string)
-		score := int(z.Score)
-		rankEntry := fmt.Sprintf("Rank %d:%s(消息数：%d)", rank, username, score)
+	for _, entry := range entries {
+		rankEntry := fmt.Sprintf("Rank %d:%s(消息数：%d)", entry.Rank, entry.Username, entry.Score)
 		rankList = append(rankList, rankEntry)
 	}
 	return rankList, nil
@@ -0,0 +1,93 @@
+package rdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// SessionKeyPrefix 会话令牌键前缀，完整键为 chat_session:<令牌ID>，值为用户名
+	SessionKeyPrefix = "chat_session:"
+	// UserSessionsKeyPrefix 用户会话集合键前缀，完整键为 chat_user_sessions:<用户名>，成员为令牌ID
+	UserSessionsKeyPrefix = "chat_user_sessions:"
+)
+
+// SaveSession 保存会话令牌，到期后自动失效
+// 参数 id 为令牌 ID，user 为用户名，ttl 为有效期
+func (rqc *RedisQueueClient) SaveSession(id, user string, ttl time.Duration) error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	userKey := UserSessionsKeyPrefix + user
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, SessionKeyPrefix+id, user, ttl)
+		pipe.SAdd(ctx, userKey, id)
+		// 用户会话集合的有效期跟随最近签发的令牌延长
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("保存会话失败：%w", err)
+	}
+	return nil
+}
+
+// LookupSession 查询会话令牌对应的用户名
+// 返回值 found 为 false 表示令牌已过期或已被吊销
+func (rqc *RedisQueueClient) LookupSession(id string) (user string, found bool, err error) {
+	if rqc == nil || rqc.Client == nil {
+		return "", false, fmt.Errorf("redis队列客户端未初始化")
+	}
+	user, err = rqc.Client.Get(context.Background(), SessionKeyPrefix+id).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("查询会话失败：%w", err)
+	}
+	return user, true, nil
+}
+
+// RevokeSession 吊销单个会话令牌
+func (rqc *RedisQueueClient) RevokeSession(id, user string) error {
+	if rqc == nil || rqc.Client == nil {
+		return fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	_, err := rqc.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, SessionKeyPrefix+id)
+		pipe.SRem(ctx, UserSessionsKeyPrefix+user, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("吊销会话失败：%w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions 吊销用户的所有会话令牌
+// 返回值为被吊销的令牌数量
+func (rqc *RedisQueueClient) RevokeUserSessions(user string) (int, error) {
+	if rqc == nil || rqc.Client == nil {
+		return 0, fmt.Errorf("redis队列客户端未初始化")
+	}
+	ctx := context.Background()
+	userKey := UserSessionsKeyPrefix + user
+	ids, err := rqc.Client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("查询用户会话失败：%w", err)
+	}
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, SessionKeyPrefix+id)
+	}
+	keys = append(keys, userKey)
+	if err := rqc.Client.Del(ctx, keys...).Err(); err != nil {
+		return 0, fmt.Errorf("吊销用户会话失败：%w", err)
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignerIssueVerify(t *testing.T) {
+	s, err := NewSigner("secret")
+	if err != nil {
+		t.Fatalf("NewSigner 失败：%v", err)
+	}
+	token, claims, err := s.Issue("bob", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue 失败：%v", err)
+	}
+	if claims.User != "bob" || claims.ID == "" {
+		t.Fatalf("签发的令牌信息不完整：%+v", claims)
+	}
+	if d := time.Until(claims.Expiry()); d <= 59*time.Minute || d > time.Hour {
+		t.Fatalf("过期时间不符合预期，剩余 %s", d)
+	}
+
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify 失败：%v", err)
+	}
+	if *got != *claims {
+		t.Fatalf("Verify 返回 %+v，期望 %+v", got, claims)
+	}
+
+	// 每次签发的令牌 ID 不同
+	_, other, err := s.Issue("bob", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue 失败：%v", err)
+	}
+	if other.ID == claims.ID {
+		t.Fatal("两次签发的令牌 ID 相同")
+	}
+}
+
+// encodeClaims 按令牌格式编码任意内容，用于构造篡改过的令牌
+func encodeClaims(t *testing.T, v any) string {
+	t.Helper()
+	payload, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("编码失败：%v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func TestSignerVerifyRejects(t *testing.T) {
+	s, _ := NewSigner("secret")
+	token, _, err := s.Issue("bob", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue 失败：%v", err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+
+	other, _ := NewSigner("other-secret")
+	otherToken, _, _ := other.Issue("bob", time.Hour)
+
+	expired, _, _ := s.Issue("bob", -time.Second)
+
+	// 修改用户名后沿用原签名
+	forgedBody := encodeClaims(t, Claims{User: "admin", ID: "x", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	// 签名正确但缺少必要字段
+	incomplete := encodeClaims(t, Claims{User: "bob", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"空令牌", ""},
+		{"缺少分隔符", body},
+		{"缺少签名", body + "."},
+		{"缺少主体", "." + sig},
+		{"篡改主体", forgedBody + "." + sig},
+		{"篡改签名", body + "." + strings.Repeat("A", len(sig))},
+		{"其他密钥签发", otherToken},
+		{"已过期", expired},
+		{"主体不是 base64", "!!!." + s.sign("!!!")},
+		{"主体不是 JSON", "bm90LWpzb24." + s.sign("bm90LWpzb24")},
+		{"缺少令牌 ID", incomplete + "." + s.sign(incomplete)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := s.Verify(tt.token); err == nil {
+				t.Fatalf("Verify(%q) 应失败，实际返回 %+v", tt.token, claims)
+			}
+		})
+	}
+}
+
+func TestNewSignerRandomSecret(t *testing.T) {
+	a, err := NewSigner("")
+	if err != nil {
+		t.Fatalf("NewSigner 失败：%v", err)
+	}
+	b, err := NewSigner("")
+	if err != nil {
+		t.Fatalf("NewSigner 失败：%v", err)
+	}
+	token, _, err := a.Issue("bob", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue 失败：%v", err)
+	}
+	// 未配置密钥时每次生成不同的随机密钥，其他实例签发的令牌无效
+	if _, err := b.Verify(token); err == nil {
+		t.Fatal("随机密钥不同的签发器不应接受对方的令牌")
+	}
+}
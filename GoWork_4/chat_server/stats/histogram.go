@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets 默认的耗时直方图桶上界（秒）
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Histogram 耗时直方图，并发安全
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // 桶上界（秒），升序
+	counts  []int64   // 每个桶（非累计）的观测次数，最后一个元素对应 +Inf
+	sum     float64   // 观测值总和（秒）
+	count   int64     // 观测总次数
+}
+
+// HistogramSnapshot 直方图快照，Counts 为累计计数，与 Buckets 一一对应
+type HistogramSnapshot struct {
+	Buckets []float64 // 桶上界（秒）
+	Counts  []int64   // 小于等于对应上界的累计观测次数
+	Sum     float64   // 观测值总和（秒）
+	Count   int64     // 观测总次数
+}
+
+// NewHistogram 使用给定的桶上界创建直方图
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)+1),
+	}
+}
+
+// Observe 记录一次耗时
+func (h *Histogram) Observe(d time.Duration) {
+	v := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.buckets) && v > h.buckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// Snapshot 返回直方图的累计快照
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snap := HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  make([]int64, len(h.buckets)),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+	var cumulative int64
+	for i := range h.buckets {
+		cumulative += h.counts[i]
+		snap.Counts[i] = cumulative
+	}
+	return snap
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromWriter 按 Prometheus 文本格式（0.0.4）输出指标
+// 同名指标的 HELP/TYPE 只输出一次，调用方需保证同名指标连续写出。
+type PromWriter struct {
+	w       io.Writer
+	written map[string]bool
+}
+
+// NewPromWriter 创建写入 w 的 Prometheus 指标输出器
+func NewPromWriter(w io.Writer) *PromWriter {
+	return &PromWriter{w: w, written: make(map[string]bool)}
+}
+
+// Gauge 输出一个 gauge 类型的样本，labels 为键值交替的标签列表
+func (p *PromWriter) Gauge(name, help string, value float64, labels ...string) {
+	p.header(name, help, "gauge")
+	p.sample(name, labels, value)
+}
+
+// Counter 输出一个 counter 类型的样本，labels 为键值交替的标签列表
+func (p *PromWriter) Counter(name, help string, value float64, labels ...string) {
+	p.header(name, help, "counter")
+	p.sample(name, labels, value)
+}
+
+// Histogram 输出一个直方图，labels 为键值交替的标签列表
+func (p *PromWriter) Histogram(name, help string, h HistogramSnapshot, labels ...string) {
+	p.header(name, help, "histogram")
+	for i, le := range h.Buckets {
+		p.sample(name+"_bucket", append(labels[:len(labels):len(labels)], "le", formatFloat(le)), float64(h.Counts[i]))
+	}
+	p.sample(name+"_bucket", append(labels[:len(labels):len(labels)], "le", "+Inf"), float64(h.Count))
+	p.sample(name+"_sum", labels, h.Sum)
+	p.sample(name+"_count", labels, float64(h.Count))
+}
+
+// header 输出指标的 HELP 和 TYPE 行
+func (p *PromWriter) header(name, help, typ string) {
+	if p.written[name] {
+		return
+	}
+	p.written[name] = true
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample 输出一行样本
+func (p *PromWriter) sample(name string, labels []string, value float64) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(labels[i])
+			sb.WriteString("=")
+			sb.WriteString(strconv.Quote(labels[i+1]))
+		}
+		sb.WriteByte('}')
+	}
+	fmt.Fprintf(p.w, "%s %s\n", sb.String(), formatFloat(value))
+}
+
+// formatFloat 按 Prometheus 习惯格式化数值
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteSnapshot 将统计快照中的指标写出
+func (p *PromWriter) WriteSnapshot(snap Snapshot) {
+	p.Gauge("chat_uptime_seconds", "服务器运行时长（秒）", snap.Uptime.Seconds())
+	p.Gauge("chat_online_users", "当前在线用户数", float64(snap.Online))
+	p.Gauge("chat_online_users_peak", "峰值在线用户数", float64(snap.PeakOnline))
+	for _, msgType := range snap.MessageTypes() {
+		p.Counter("chat_messages_total", "按类型统计的消息总数", float64(snap.MessagesByType[msgType]), "type", msgType)
+	}
+	p.Counter("chat_logins_total", "登录次数", float64(snap.LoginSuccess), "result", "success")
+	p.Counter("chat_logins_total", "登录次数", float64(snap.LoginFailure), "result", "failure")
+	p.Counter("chat_redis_enqueue_failures_total", "Redis 入队失败次数", float64(snap.EnqueueFailures))
+	p.Counter("chat_broadcasts_total", "广播次数", float64(snap.FanoutCount))
+	p.Counter("chat_broadcast_recipients_total", "广播投递的连接总数", float64(snap.FanoutRecipients))
+	p.Histogram("chat_broadcast_duration_seconds", "单次广播耗时", snap.FanoutLatency)
+
+	ops := make([]string, 0, len(snap.DBQueryLatency))
+	for op := range snap.DBQueryLatency {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		p.Histogram("chat_mysql_query_duration_seconds", "MySQL 查询耗时", snap.DBQueryLatency[op], "op", op)
+	}
+}
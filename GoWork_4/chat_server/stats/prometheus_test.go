@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(p *PromWriter)
+		want  string
+	}{
+		{
+			name:  "gauge",
+			write: func(p *PromWriter) { p.Gauge("chat_online_users", "当前在线用户数", 3) },
+			want: `# HELP chat_online_users 当前在线用户数
+# TYPE chat_online_users gauge
+chat_online_users 3
+`,
+		},
+		{
+			name: "同名 counter 只输出一次 HELP 和 TYPE",
+			write: func(p *PromWriter) {
+				p.Counter("chat_logins_total", "登录次数", 5, "result", "success")
+				p.Counter("chat_logins_total", "登录次数", 1, "result", "failure")
+			},
+			want: `# HELP chat_logins_total 登录次数
+# TYPE chat_logins_total counter
+chat_logins_total{result="success"} 5
+chat_logins_total{result="failure"} 1
+`,
+		},
+		{
+			name:  "多个标签和需要转义的标签值",
+			write: func(p *PromWriter) { p.Counter("m", "h", 1, "a", "x", "b", `say "hi"`) },
+			want: `# HELP m h
+# TYPE m counter
+m{a="x",b="say \"hi\""} 1
+`,
+		},
+		{
+			name:  "小数",
+			write: func(p *PromWriter) { p.Gauge("chat_uptime_seconds", "运行时长", 1.5) },
+			want: `# HELP chat_uptime_seconds 运行时长
+# TYPE chat_uptime_seconds gauge
+chat_uptime_seconds 1.5
+`,
+		},
+		{
+			name: "直方图",
+			write: func(p *PromWriter) {
+				p.Histogram("d", "耗时", HistogramSnapshot{
+					Buckets: []float64{0.01, 0.1},
+					Counts:  []int64{1, 3},
+					Sum:     0.25,
+					Count:   4,
+				}, "op", "q")
+			},
+			want: `# HELP d 耗时
+# TYPE d histogram
+d_bucket{op="q",le="0.01"} 1
+d_bucket{op="q",le="0.1"} 3
+d_bucket{op="q",le="+Inf"} 4
+d_sum{op="q"} 0.25
+d_count{op="q"} 4
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			tt.write(NewPromWriter(&sb))
+			if got := sb.String(); got != tt.want {
+				t.Fatalf("输出不符合预期：\n%s\n期望：\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromWriterHistogramDoesNotModifyLabels(t *testing.T) {
+	// 直方图为每个桶追加 le 标签，不应改写调用方传入的标签切片
+	labels := make([]string, 2, 8)
+	labels[0], labels[1] = "op", "q"
+	var sb strings.Builder
+	NewPromWriter(&sb).Histogram("d", "耗时", HistogramSnapshot{Buckets: []float64{1}, Counts: []int64{0}}, labels...)
+	if extended := labels[:4]; extended[2] != "" || extended[3] != "" {
+		t.Fatalf("标签切片的底层数组被修改：%q", extended)
+	}
+}
+
+func TestHistogramSnapshot(t *testing.T) {
+	h := NewHistogram([]float64{0.01, 0.1})
+	for _, ms := range []int{5, 10, 50, 500} {
+		h.Observe(time.Duration(ms) * time.Millisecond)
+	}
+	snap := h.Snapshot()
+	// 桶上界包含等于上界的观测值，计数为累计值
+	if snap.Counts[0] != 2 || snap.Counts[1] != 3 || snap.Count != 4 {
+		t.Fatalf("直方图快照不符合预期：%+v", snap)
+	}
+}
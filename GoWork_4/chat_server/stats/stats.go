@@ -0,0 +1,210 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// minuteWindow 消息速率统计保留的分钟数
+const minuteWindow = 60
+
+// minuteBucket 某一分钟内的消息计数
+type minuteBucket struct {
+	minute int64 // Unix 分钟数，用于判断桶是否过期
+	count  int64 // 该分钟内的消息数
+}
+
+// Collector 服务器运行统计收集器
+// 所有方法均为并发安全，可在各个处理协程中直接调用。
+type Collector struct {
+	mu        sync.Mutex
+	startedAt time.Time
+
+	buckets        [minuteWindow]minuteBucket // 最近一小时每分钟的消息数（环形缓冲）
+	messagesByType map[string]int64           // 按消息类型统计的消息总数
+
+	online     int64 // 当前在线人数
+	peakOnline int64 // 峰值在线人数
+
+	loginSuccess    int64 // 登录成功次数
+	loginFailure    int64 // 登录失败次数
+	enqueueFailures int64 // Redis 入队失败次数
+
+	fanoutCount      int64         // 广播次数
+	fanoutRecipients int64         // 广播投递的连接总数
+	fanoutTotal      time.Duration // 广播总耗时
+	fanoutMax        time.Duration // 单次广播最大耗时
+	fanoutLast       time.Duration // 最近一次广播耗时
+	fanoutLatency    *Histogram    // 广播耗时分布
+
+	dbLatency map[string]*Histogram // 按操作统计的 MySQL 查询耗时分布
+}
+
+// Snapshot 某一时刻的统计快照
+type Snapshot struct {
+	Uptime            time.Duration                // 运行时长
+	MessagesLastMin   int64                        // 最近一分钟的消息数
+	MessagesPerMinAvg float64                      // 最近一小时平均每分钟消息数
+	MessagesByType    map[string]int64             // 按类型统计的消息总数
+	Online            int64                        // 当前在线人数
+	PeakOnline        int64                        // 峰值在线人数
+	LoginSuccess      int64                        // 登录成功次数
+	LoginFailure      int64                        // 登录失败次数
+	PrivateMessages   int64                        // 私聊消息总数
+	EnqueueFailures   int64                        // Redis 入队失败次数
+	FanoutCount       int64                        // 广播次数
+	FanoutAvg         time.Duration                // 广播平均耗时
+	FanoutMax         time.Duration                // 广播最大耗时
+	FanoutLast        time.Duration                // 最近一次广播耗时
+	FanoutRecipients  int64                        // 广播投递的连接总数
+	FanoutLatency     HistogramSnapshot            // 广播耗时分布
+	DBQueryLatency    map[string]HistogramSnapshot // 按操作统计的 MySQL 查询耗时分布
+}
+
+// NewCollector 创建统计收集器
+func NewCollector() *Collector {
+	return &Collector{
+		startedAt:      time.Now(),
+		messagesByType: make(map[string]int64),
+		fanoutLatency:  NewHistogram(DefaultLatencyBuckets),
+		dbLatency:      make(map[string]*Histogram),
+	}
+}
+
+// RecordMessage 记录一条进入消息处理流程的消息
+// 参数 msgType 为消息类型，chat 和 private 类型计入消息速率
+func (c *Collector) RecordMessage(msgType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messagesByType[msgType]++
+	if msgType != "chat" && msgType != "private" {
+		return
+	}
+	minute := time.Now().Unix() / 60
+	bucket := &c.buckets[minute%minuteWindow]
+	if bucket.minute != minute {
+		bucket.minute = minute
+		bucket.count = 0
+	}
+	bucket.count++
+}
+
+// RecordLogin 记录一次登录结果
+func (c *Collector) RecordLogin(success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if success {
+		c.loginSuccess++
+	} else {
+		c.loginFailure++
+	}
+}
+
+// RecordEnqueueFailure 记录一次 Redis 入队失败
+func (c *Collector) RecordEnqueueFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enqueueFailures++
+}
+
+// SetOnline 更新当前在线人数，并维护峰值
+func (c *Collector) SetOnline(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.online = int64(n)
+	if c.online > c.peakOnline {
+		c.peakOnline = c.online
+	}
+}
+
+// ObserveFanout 记录一次广播的耗时和投递的连接数
+func (c *Collector) ObserveFanout(d time.Duration, recipients int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fanoutCount++
+	c.fanoutRecipients += int64(recipients)
+	c.fanoutTotal += d
+	c.fanoutLast = d
+	if d > c.fanoutMax {
+		c.fanoutMax = d
+	}
+	c.fanoutLatency.Observe(d)
+}
+
+// ObserveDBQuery 记录一次 MySQL 查询耗时
+// 参数 op 为操作名称，如 check_credentials
+func (c *Collector) ObserveDBQuery(op string, d time.Duration) {
+	c.mu.Lock()
+	h, ok := c.dbLatency[op]
+	if !ok {
+		h = NewHistogram(DefaultLatencyBuckets)
+		c.dbLatency[op] = h
+	}
+	c.mu.Unlock()
+	h.Observe(d)
+}
+
+// Snapshot 返回当前统计数据的快照
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().Unix() / 60
+	var lastMin, lastHour int64
+	for _, bucket := range c.buckets {
+		if bucket.minute > now-minuteWindow && bucket.minute <= now {
+			lastHour += bucket.count
+		}
+		// 最近一分钟取上一个完整分钟与当前分钟中较大的一个，避免刚进入新分钟时读数为 0
+		if bucket.minute == now || bucket.minute == now-1 {
+			lastMin = max(lastMin, bucket.count)
+		}
+	}
+
+	// 统计时长不足一小时时按实际时长计算平均值
+	uptime := time.Since(c.startedAt)
+	minutes := min(max(uptime.Minutes(), 1), minuteWindow)
+
+	byType := make(map[string]int64, len(c.messagesByType))
+	for k, v := range c.messagesByType {
+		byType[k] = v
+	}
+
+	snap := Snapshot{
+		Uptime:            uptime,
+		MessagesLastMin:   lastMin,
+		MessagesPerMinAvg: float64(lastHour) / minutes,
+		MessagesByType:    byType,
+		Online:            c.online,
+		PeakOnline:        c.peakOnline,
+		LoginSuccess:      c.loginSuccess,
+		LoginFailure:      c.loginFailure,
+		PrivateMessages:   c.messagesByType["private"],
+		EnqueueFailures:   c.enqueueFailures,
+		FanoutCount:       c.fanoutCount,
+		FanoutMax:         c.fanoutMax,
+		FanoutLast:        c.fanoutLast,
+		FanoutRecipients:  c.fanoutRecipients,
+		FanoutLatency:     c.fanoutLatency.Snapshot(),
+		DBQueryLatency:    make(map[string]HistogramSnapshot, len(c.dbLatency)),
+	}
+	for op, h := range c.dbLatency {
+		snap.DBQueryLatency[op] = h.Snapshot()
+	}
+	if c.fanoutCount > 0 {
+		snap.FanoutAvg = c.fanoutTotal / time.Duration(c.fanoutCount)
+	}
+	return snap
+}
+
+// MessageTypes 返回快照中出现过的消息类型，按名称排序
+func (s Snapshot) MessageTypes() []string {
+	types := make([]string, 0, len(s.MessagesByType))
+	for t := range s.MessagesByType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
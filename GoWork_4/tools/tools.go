@@ -2,17 +2,127 @@ package tools
 
 import (
 	"bufio"
+	"cmp"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"os"
+	"strconv"
 	"strings"
+
+	"golang.org/x/term"
 )
 
 // 消息头长度（4字节，存储消息体长度）
 const headerSize = 4
 
+// SessionTokenPrefix 服务器下发会话令牌时使用的消息前缀
+// 客户端收到以此开头的消息时保存令牌，不显示给用户
+const SessionTokenPrefix = "\x1bSESSION "
+
+// ResumeCommand 客户端在主菜单中恢复会话时发送的命令，格式为 "/resume <令牌>"
+const ResumeCommand = "/resume"
+
+// TakeoverNotice 同一账号在别处登录、当前连接被踢出时服务器发送的通知
+// 客户端收到后不再自动重连，避免两端互相踢出
+const TakeoverNotice = "系统：您的账号已在别处登录，当前连接已断开"
+
+// TakeoverPrompt 同一账号已在线时，服务器询问新登录是否顶替原会话的提示中包含的文本
+// 客户端据此识别该提示并等待用户回答 y/n
+const TakeoverPrompt = "是否断开原会话并在此登录"
+
+// KickNotice 管理员将用户移出聊天室时服务器发送的通知，后面可能附带原因
+const KickNotice = "系统：您已被管理员移出聊天室"
+
+// IsTerminalNotice 判断消息是否为服务器主动断开连接前发送的最终通知
+// 客户端收到后不再自动重连，否则会凭令牌立即恢复会话
+func IsTerminalNotice(msg string) bool {
+	return msg == TakeoverNotice || strings.HasPrefix(msg, KickNotice)
+}
+
+// InputPromptPrefix 服务器在命令执行过程中等待用户回答（如二次确认）时使用的消息前缀
+const InputPromptPrefix = "\x1bPROMPT "
+
+// PasswordPromptPrefix 服务器在命令执行过程中要求输入密码时使用的消息前缀
+// 客户端收到后以不回显的方式读取下一行输入作为回答，而不是当作聊天消息发送
+const PasswordPromptPrefix = "\x1bPASSWORD "
+
+// FormatInputPrompt 生成等待用户回答的提示消息
+func FormatInputPrompt(text string) string {
+	return InputPromptPrefix + text
+}
+
+// FormatPasswordPrompt 生成要求输入密码的提示消息
+func FormatPasswordPrompt(text string) string {
+	return PasswordPromptPrefix + text
+}
+
+// ParsePrompt 从服务器消息中提取提示文本，password 表示需要输入密码，消息不是提示消息时 ok 为 false
+func ParsePrompt(msg string) (text string, password, ok bool) {
+	if text, ok := strings.CutPrefix(msg, PasswordPromptPrefix); ok {
+		return text, true, true
+	}
+	if text, ok := strings.CutPrefix(msg, InputPromptPrefix); ok {
+		return text, false, true
+	}
+	return msg, false, false
+}
+
+// StreamIDSeparator 聊天消息中分隔 Stream ID 与正文的控制字符
+const StreamIDSeparator = "\x1f"
+
+// FormatStreamMessage 为消息附加 Redis Stream ID，格式为 "\x1f<ID>\x1f<正文>"
+func FormatStreamMessage(id, text string) string {
+	return StreamIDSeparator + id + StreamIDSeparator + text
+}
+
+// ParseStreamMessage 拆分附带 Stream ID 的消息，普通消息返回 ok 为 false，text 为原消息
+func ParseStreamMessage(msg string) (id, text string, ok bool) {
+	rest, found := strings.CutPrefix(msg, StreamIDSeparator)
+	if !found {
+		return "", msg, false
+	}
+	id, text, found = strings.Cut(rest, StreamIDSeparator)
+	if !found {
+		return "", msg, false
+	}
+	return id, text, true
+}
+
+// CompareStreamIDs 比较两个 Stream 消息 ID（格式为 毫秒时间戳-序号）
+// 返回值小于 0 表示 a 早于 b，等于 0 表示相同，大于 0 表示 a 晚于 b
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq := splitStreamID(a)
+	bMs, bSeq := splitStreamID(b)
+	if aMs != bMs {
+		return cmp.Compare(aMs, bMs)
+	}
+	return cmp.Compare(aSeq, bSeq)
+}
+
+// splitStreamID 将 Stream 消息 ID 拆分为毫秒时间戳和序号，格式错误的部分按 0 处理
+func splitStreamID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+// FormatSessionToken 生成下发会话令牌的消息
+func FormatSessionToken(token string) string {
+	return SessionTokenPrefix + token
+}
+
+// ParseSessionToken 从服务器消息中提取会话令牌，消息不是令牌消息时返回 false
+func ParseSessionToken(msg string) (string, bool) {
+	if !strings.HasPrefix(msg, SessionTokenPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(msg, SessionTokenPrefix), true
+}
+
 // SendMessage 发送带长度的消息（解决粘包）
 func SendMessage(conn net.Conn, message string) error {
 	// 将消息转换为字节
@@ -35,25 +145,24 @@ func SendMessage(conn net.Conn, message string) error {
 		netErr, ok := err.(net.Error)
 		if ok {
 			if netErr.Timeout() {
-				fmt.Printf("发送消息超时: %s, 消息长度: %d\n", message[:min(len(message), 50)], bodyLen)
+				slog.Warn("发送消息超时", "remote_addr", conn.RemoteAddr().String(), "length", bodyLen)
 			} else if netErr.Temporary() {
-				fmt.Printf("临时网络错误: %v, 消息长度: %d\n", netErr, bodyLen)
+				slog.Warn("临时网络错误", "remote_addr", conn.RemoteAddr().String(), "length", bodyLen, "error", netErr)
 			}
 		}
 		// 记录基础错误信息
-		fmt.Printf("发送消息失败: %v, 消息: %.50s..., 长度: %d\n", err, message, bodyLen)
+		slog.Warn("发送消息失败", "remote_addr", conn.RemoteAddr().String(), "length", bodyLen, "error", err)
 		return err
 	}
 	return nil
 }
 
 // ReceiveMessage 接收带长度的消息（解决粘包）
+// 直接从连接按长度读取，不使用带缓冲的 Reader，避免预读走后续消息导致丢失
 func ReceiveMessage(conn net.Conn) (string, error) {
-	reader := bufio.NewReader(conn)
-
 	// 1. 先读取消息头（4字节长度）
 	header := make([]byte, headerSize)
-	_, err := io.ReadFull(reader, header)
+	_, err := io.ReadFull(conn, header)
 	if err != nil {
 		return "", err
 	}
@@ -63,7 +172,7 @@ func ReceiveMessage(conn net.Conn) (string, error) {
 
 	// 3. 读取消息体
 	body := make([]byte, bodyLen)
-	_, err = io.ReadFull(reader, body)
+	_, err = io.ReadFull(conn, body)
 	if err != nil {
 		return "", err
 	}
@@ -85,3 +194,19 @@ func ReadInput(prompt string) (string, error) {
 	}
 	return "", scanner.Err()
 }
+
+// ReadPassword 读取密码输入，终端下不回显输入内容
+// 标准输入不是终端（如管道重定向）时退化为 ReadInput
+func ReadPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return ReadInput(prompt)
+	}
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Println() // 不回显时换行也不会显示，手动补上
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(password)), nil
+}